@@ -0,0 +1,160 @@
+// Command rngcert streams output from the draw CSPRNG through a subset of the
+// NIST SP 800-22 randomness tests and writes an Ed25519-signed JSON report for
+// lottery certification packs.
+//
+//	rngcert -bits 1000000 -out rng-report.json
+//	rngcert -genkey
+//
+// The signing key is a base64 Ed25519 seed taken from -key or RNG_REPORT_SIGNING_KEY.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/rng"
+)
+
+// report is the signed body; its exact JSON encoding is what the signature covers.
+type report struct {
+	Generator   string           `json:"generator"`
+	Standard    string           `json:"standard"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Sequences   int              `json:"sequences"`
+	BitsPerSeq  int              `json:"bits_per_sequence"`
+	Alpha       float64          `json:"alpha"`
+	Sequence    []sequenceResult `json:"results"`
+	Proportions []proportion     `json:"proportions"`
+	Passed      bool             `json:"passed"`
+}
+
+// proportion is SP 800-22 section 4.2.1: the share of sequences passing a test
+// must reach (1-alpha) - 3*sqrt(alpha*(1-alpha)/sequences).
+type proportion struct {
+	Test     string  `json:"test"`
+	Passed   int     `json:"passed"`
+	Minimum  float64 `json:"minimum_proportion"`
+	Observed float64 `json:"observed_proportion"`
+	OK       bool    `json:"ok"`
+}
+
+type sequenceResult struct {
+	Index int              `json:"index"`
+	Tests []rng.NISTResult `json:"tests"`
+}
+
+type signedReport struct {
+	Report    json.RawMessage `json:"report"`
+	Algorithm string          `json:"signature_algorithm"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+}
+
+func main() {
+	bits := flag.Int("bits", rng.NISTMinBits, "bits per tested sequence (at least 1000000)")
+	sequences := flag.Int("sequences", 1, "number of independent sequences to test")
+	out := flag.String("out", "rng-report.json", "report output path")
+	keyFlag := flag.String("key", "", "base64 Ed25519 seed (defaults to RNG_REPORT_SIGNING_KEY)")
+	genKey := flag.Bool("genkey", false, "print a new signing seed and its public key, then exit")
+	flag.Parse()
+
+	if *genKey {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatalf("generating key: %v", err)
+		}
+		fmt.Printf("RNG_REPORT_SIGNING_KEY=%s\n", base64.StdEncoding.EncodeToString(priv.Seed()))
+		fmt.Printf("public key: %s\n", base64.StdEncoding.EncodeToString(pub))
+		return
+	}
+
+	seedB64 := *keyFlag
+	if seedB64 == "" {
+		seedB64 = config.Load().RNGReportKey
+	}
+	seed, err := base64.StdEncoding.DecodeString(seedB64)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		log.Fatalf("a %d-byte base64 Ed25519 seed is required (-key or RNG_REPORT_SIGNING_KEY); use -genkey to create one", ed25519.SeedSize)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+
+	if *bits < rng.NISTMinBits || *sequences < 1 {
+		log.Fatalf("need at least %d bits and one sequence", rng.NISTMinBits)
+	}
+
+	gen, err := rng.NewCSPRNG()
+	if err != nil {
+		log.Fatalf("initialising CSPRNG: %v", err)
+	}
+
+	rep := report{
+		Generator:   "AES-256-CTR CSPRNG (internal/rng)",
+		Standard:    "NIST SP 800-22 rev1a (subset)",
+		GeneratedAt: time.Now().UTC(),
+		Sequences:   *sequences,
+		BitsPerSeq:  *bits,
+		Alpha:       rng.NISTAlpha,
+		Passed:      true,
+	}
+	passes := make(map[string]int)
+	var order []string
+	for i := 0; i < *sequences; i++ {
+		seq, err := rng.ReadBits(gen, *bits)
+		if err != nil {
+			log.Fatalf("reading CSPRNG output: %v", err)
+		}
+		results := rng.RunNISTSuite(seq)
+		for _, r := range results {
+			if i == 0 {
+				order = append(order, r.Name)
+			}
+			if r.Passed {
+				passes[r.Name]++
+			}
+			log.Printf("sequence %d: %-40s passed=%-5v p=%v", i+1, r.Name, r.Passed, r.PValues)
+		}
+		rep.Sequence = append(rep.Sequence, sequenceResult{Index: i + 1, Tests: results})
+	}
+
+	a, n := rng.NISTAlpha, float64(*sequences)
+	minimum := (1 - a) - 3*math.Sqrt(a*(1-a)/n)
+	for _, name := range order {
+		observed := float64(passes[name]) / n
+		p := proportion{Test: name, Passed: passes[name], Minimum: minimum, Observed: observed, OK: observed >= minimum}
+		if !p.OK {
+			rep.Passed = false
+		}
+		rep.Proportions = append(rep.Proportions, p)
+	}
+
+	body, err := json.Marshal(rep)
+	if err != nil {
+		log.Fatalf("encoding report: %v", err)
+	}
+	signed := signedReport{
+		Report:    body,
+		Algorithm: "Ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, body)),
+	}
+	encoded, err := json.Marshal(signed)
+	if err != nil {
+		log.Fatalf("encoding signed report: %v", err)
+	}
+	if err := os.WriteFile(*out, encoded, 0o644); err != nil {
+		log.Fatalf("writing report: %v", err)
+	}
+	log.Printf("report written to %s (overall passed=%v)", *out, rep.Passed)
+	if !rep.Passed {
+		os.Exit(1)
+	}
+}
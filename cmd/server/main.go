@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/ArowuTest/promo-backend/internal/auth"
 	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/dropfolder"
 	"github.com/ArowuTest/promo-backend/internal/handlers"
+	"github.com/ArowuTest/promo-backend/internal/mail"
 	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rbac"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -16,7 +20,25 @@ func main() {
 	appCfg := config.Load()
 	db := config.InitDB(appCfg)
 	models.Migrate(db)
+	if err := rbac.Seed(db); err != nil {
+		log.Fatalf("seeding roles failed: %v", err)
+	}
 	auth.Init(appCfg.JWTSecret)
+	auth.InitSealKey(appCfg.MFASecretKey)
+	mailer, err := mail.NewSender(appCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handlers.Mailer = mailer
+
+	if appCfg.IngestDropDir != "" {
+		ingester := dropfolder.New(db, appCfg.IngestDropDir, appCfg.IngestPollInterval)
+		go func() {
+			if err := ingester.Run(context.Background()); err != nil {
+				log.Printf("drop-folder ingester stopped: %v", err)
+			}
+		}()
+	}
 
 	r := gin.Default()
 	r.Use(cors.New(cors.Config{
@@ -30,36 +52,127 @@ func main() {
 	apiV1 := r.Group("/api/v1")
 	{
 		apiV1.POST("/admin/login", handlers.Login)
+		apiV1.POST("/admin/login/mfa", handlers.LoginMFA)
+		apiV1.POST("/auth/refresh", handlers.RefreshSession)
+		apiV1.POST("/auth/password/reset", handlers.ResetPassword)
+		apiV1.POST("/ingest/recharges", handlers.RequireIngestSignature(), handlers.IngestRecharges)
 
 		authGroup := apiV1.Group("/")
 		authGroup.Use(handlers.RequireAuth())
+		authGroup.POST("/auth/logout", handlers.Logout)
+		authGroup.POST("/auth/password", handlers.ChangePassword)
+
+		mfaRoutes := authGroup.Group("/auth/mfa")
+		{
+			mfaRoutes.GET("", handlers.GetMFAStatus)
+			mfaRoutes.POST("/enroll", handlers.EnrollMFA)
+			mfaRoutes.POST("/confirm", handlers.ConfirmMFA)
+			mfaRoutes.POST("/recovery-codes", handlers.RegenerateRecoveryCodes)
+			mfaRoutes.DELETE("", handlers.DisableMFA)
+		}
 
 		userRoutes := authGroup.Group("/admin/users")
-		userRoutes.Use(handlers.RequireAuth(models.RoleSuperAdmin))
+		userRoutes.Use(handlers.RequirePermission(rbac.UsersManage))
 		{
 			userRoutes.POST("", handlers.CreateUser)
 			userRoutes.GET("", handlers.ListUsers)
 			userRoutes.GET("/:id", handlers.GetUser)
 			userRoutes.PUT("/:id", handlers.UpdateUser)
 			userRoutes.DELETE("/:id", handlers.DeleteUser)
+			userRoutes.POST("/:id/mfa/reset", handlers.ResetUserMFA)
+			userRoutes.POST("/:id/unlock", handlers.UnlockUser)
+			userRoutes.POST("/:id/password-reset", handlers.IssuePasswordReset)
+		}
+
+		roleRoutes := authGroup.Group("/roles")
+		roleRoutes.Use(handlers.RequirePermission(rbac.RolesManage))
+		{
+			roleRoutes.GET("", handlers.ListRoles)
+			roleRoutes.GET("/permissions", handlers.ListPermissions)
+			roleRoutes.POST("", handlers.CreateRole)
+			roleRoutes.PUT("/:name", handlers.UpdateRole)
+			roleRoutes.DELETE("/:name", handlers.DeleteRole)
+		}
+
+		campaignRoutes := authGroup.Group("/campaigns")
+		campaignRoutes.Use(handlers.RequirePermission(rbac.CampaignsManage))
+		{
+			campaignRoutes.POST("", handlers.CreateCampaign)
+			campaignRoutes.GET("", handlers.ListCampaigns)
+			campaignRoutes.GET("/:id", handlers.GetCampaign)
+			campaignRoutes.PUT("/:id", handlers.UpdateCampaign)
 		}
 
 		prizeRoutes := authGroup.Group("/prize-structures")
-		prizeRoutes.Use(handlers.RequireAuth(models.RoleSuperAdmin, models.RoleAdmin))
+		prizeRoutes.Use(handlers.RequirePermission(rbac.PrizesManage))
 		{
 			prizeRoutes.POST("", handlers.CreatePrizeStructure)
 			prizeRoutes.GET("", handlers.ListPrizeStructures)
+			prizeRoutes.GET("/resolve", handlers.ResolvePrizeStructure)
 			prizeRoutes.GET("/:id", handlers.GetPrizeStructure)
 			prizeRoutes.PUT("/:id", handlers.UpdatePrizeStructure)
 			prizeRoutes.DELETE("/:id", handlers.DeletePrizeStructure)
 		}
 
+		calendarRoutes := authGroup.Group("/calendar")
+		calendarRoutes.Use(handlers.RequirePermission(rbac.CalendarManage))
+		{
+			calendarRoutes.GET("", handlers.ListCalendarEntries)
+			calendarRoutes.POST("", handlers.CreateCalendarEntry)
+			calendarRoutes.DELETE("/:id", handlers.DeleteCalendarEntry)
+		}
+
+		exclusionRoutes := authGroup.Group("/exclusions")
+		exclusionRoutes.Use(handlers.RequirePermission(rbac.ExclusionsManage))
+		{
+			exclusionRoutes.GET("", handlers.ListExclusions)
+			exclusionRoutes.POST("", handlers.CreateExclusion)
+			exclusionRoutes.POST("/import", handlers.ImportExclusions)
+			exclusionRoutes.DELETE("/:id", handlers.DeleteExclusion)
+		}
+
+		rngRoutes := authGroup.Group("/rng")
+		{
+			rngRoutes.GET("/health", handlers.RequirePermission(rbac.RNGView), handlers.RNGHealth)
+			rngRoutes.POST("/recover", handlers.RequirePermission(rbac.RNGRecover), handlers.RecoverRNG)
+		}
+
+		pointsRoutes := authGroup.Group("/points-rules")
+		pointsRoutes.Use(handlers.RequirePermission(rbac.PointsManage))
+		{
+			pointsRoutes.GET("", handlers.ListPointsRules)
+			pointsRoutes.POST("", handlers.CreatePointsRules)
+			pointsRoutes.POST("/preview", handlers.PreviewPoints)
+			pointsRoutes.GET("/:id", handlers.GetPointsRules)
+		}
+
+		ledgerRoutes := authGroup.Group("/ledger")
+		{
+			ledgerRoutes.GET("/jobs", handlers.RequirePermission(rbac.LedgerView), handlers.ListIngestionJobs)
+			ledgerRoutes.GET("/jobs/:id", handlers.RequirePermission(rbac.LedgerView), handlers.GetIngestionJob)
+			ledgerRoutes.POST("/uploads", handlers.RequirePermission(rbac.LedgerUpload), handlers.UploadEntryFile)
+			ledgerRoutes.GET("/pool", handlers.RequirePermission(rbac.ReportsView), handlers.GetLedgerPool)
+			ledgerRoutes.GET("/pool/stats", handlers.RequirePermission(rbac.ReportsView), handlers.GetPoolStats)
+			ledgerRoutes.POST("/sync/posthog", handlers.RequirePermission(rbac.LedgerSync), handlers.SyncPostHogLedger)
+		}
+
+		fraudRoutes := authGroup.Group("/fraud")
+		{
+			fraudRoutes.GET("/settings", handlers.RequirePermission(rbac.FraudView), handlers.GetFraudSettings)
+			fraudRoutes.PUT("/settings", handlers.RequirePermission(rbac.FraudConfigure), handlers.UpdateFraudSettings)
+			fraudRoutes.POST("/flags/:id/review", handlers.RequirePermission(rbac.DrawsApprove), handlers.ReviewFraudFlag)
+		}
+
 		drawRoutes := authGroup.Group("/draws")
 		{
-			drawRoutes.GET("", handlers.RequireAuth(models.RoleSuperAdmin, models.RoleAdmin, models.RoleSeniorUser), handlers.ListDraws)
-			drawRoutes.GET("/:id/winners", handlers.RequireAuth(models.RoleSuperAdmin, models.RoleAdmin, models.RoleSeniorUser), handlers.ListWinners)
-			drawRoutes.POST("/execute", handlers.RequireAuth(models.RoleSuperAdmin), handlers.ExecuteDraw)
-			drawRoutes.POST("/rerun/:id", handlers.RequireAuth(models.RoleSuperAdmin), handlers.RerunDraw)
+			drawRoutes.GET("", handlers.RequirePermission(rbac.DrawsView), handlers.ListDraws)
+			drawRoutes.GET("/:id/winners", handlers.RequirePermission(rbac.WinnersView), handlers.ListWinners)
+			drawRoutes.GET("/:id/trace", handlers.RequirePermission(rbac.DrawsAudit), handlers.GetDrawTrace)
+			drawRoutes.GET("/:id/fraud-flags", handlers.RequirePermission(rbac.FraudView), handlers.ListDrawFraudFlags)
+			drawRoutes.POST("/execute", handlers.RequirePermission(rbac.DrawsExecute), handlers.ExecuteDraw)
+			drawRoutes.POST("/batch", handlers.RequirePermission(rbac.DrawsExecute), handlers.ExecuteDrawBatch)
+			drawRoutes.POST("/simulate", handlers.RequirePermission(rbac.DrawsSimulate), handlers.SimulateDraw)
+			drawRoutes.POST("/rerun/:id", handlers.RequirePermission(rbac.DrawsExecute), handlers.RerunDraw)
 		}
 	}
 
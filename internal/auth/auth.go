@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -15,21 +19,24 @@ func Init(secret string) {
 	JWTSecret = []byte(secret)
 }
 
-// Claims defines the payload we embed in the token.
+// Claims defines the payload we embed in the token. SessionID names the
+// server-side session the token was issued under.
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.StandardClaims
 }
 
-// GenerateJWT creates a signed token valid for ttl duration.
-func GenerateJWT(userID, username, role string, ttl time.Duration) (string, error) {
+// GenerateJWT creates a signed access token for sessionID valid for ttl duration.
+func GenerateJWT(userID, username, role, sessionID string, ttl time.Duration) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  now.Unix(),
 			ExpiresAt: now.Add(ttl).Unix(),
@@ -58,3 +65,20 @@ func ParseAndVerify(tokenStr string) (*Claims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+// NewOpaqueToken returns a random URL-safe token and the hash to store in its
+// place, for refresh and other one-time tokens.
+func NewOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the stored form of an opaque token.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Password policy limits. bcrypt ignores bytes past 72, so longer passwords
+// would only appear stronger than they are.
+const (
+	PasswordMinLength = 12
+	PasswordMaxBytes  = 72
+	// passwordMinClasses of lower case, upper case, digits and symbols must appear.
+	passwordMinClasses = 3
+)
+
+// ErrWeakPassword wraps every policy violation.
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+// ValidatePassword checks pw against the policy. The username and email of
+// the account, when given, must not appear in it.
+func ValidatePassword(pw string, identities ...string) error {
+	if len([]rune(pw)) < PasswordMinLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, PasswordMinLength)
+	}
+	if len(pw) > PasswordMaxBytes {
+		return fmt.Errorf("%w: at most %d bytes allowed", ErrWeakPassword, PasswordMaxBytes)
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < passwordMinClasses {
+		return fmt.Errorf("%w: use at least %d of lower case, upper case, digits and symbols", ErrWeakPassword, passwordMinClasses)
+	}
+	folded := strings.ToLower(pw)
+	for _, id := range identities {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if local, _, ok := strings.Cut(id, "@"); ok {
+			id = local
+		}
+		if len(id) >= 3 && strings.Contains(folded, id) {
+			return fmt.Errorf("%w: must not contain the username or email", ErrWeakPassword)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// sealKey encrypts secrets stored at rest, such as TOTP secrets.
+var sealKey []byte
+
+// InitSealKey derives the at-rest encryption key from key material (call from main).
+func InitSealKey(material string) {
+	sum := sha256.Sum256([]byte(material))
+	sealKey = sum[:]
+}
+
+// Seal encrypts plain with AES-GCM under the seal key.
+func Seal(plain string) (string, error) {
+	gcm, err := sealCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(sealed string) (string, error) {
+	gcm, err := sealCipher()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return "", errors.New("auth: malformed sealed value")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("auth: sealed value cannot be opened with the configured key")
+	}
+	return string(plain), nil
+}
+
+func sealCipher() (cipher.AEAD, error) {
+	if len(sealKey) == 0 {
+		return nil, errors.New("auth: seal key not initialised")
+	}
+	block, err := aes.NewCipher(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
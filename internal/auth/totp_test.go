@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 appendix B test vectors,
+// the ASCII string "12345678901234567890", in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTOTPKnownAnswers(t *testing.T) {
+	// RFC 6238 lists 8-digit codes; a 6-digit code is their last six digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		step, ok := VerifyTOTP(rfc6238Secret, tt.code, at, 0)
+		if !ok {
+			t.Errorf("code %s at %d refused", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("code %s at %d matched step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestVerifyTOTPInput(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+	tests := []struct {
+		name, secret, code string
+		want               bool
+	}{
+		{"spaces in the code", rfc6238Secret, " 081 804 ", true},
+		{"lower-case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "081804", true},
+		{"wrong code", rfc6238Secret, "081805", false},
+		{"short code", rfc6238Secret, "81804", false},
+		{"8-digit code", rfc6238Secret, "07081804", false},
+		{"invalid secret", "not base32!", "081804", false},
+	}
+	for _, tt := range tests {
+		if _, ok := VerifyTOTP(tt.secret, tt.code, at, 0); ok != tt.want {
+			t.Errorf("%s: accepted = %v, want %v", tt.name, ok, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	// 1111111109 falls in step 37037036, whose code is 081804.
+	const step = 1111111109 / totpPeriod
+	stepStart := time.Unix(step*totpPeriod, 0)
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"start of the step", stepStart, true},
+		{"end of the step", stepStart.Add(totpPeriod*time.Second - time.Second), true},
+		{"one step early", stepStart.Add(-totpPeriod * time.Second), true},
+		{"one step late", stepStart.Add(2*totpPeriod*time.Second - time.Second), true},
+		{"two steps early", stepStart.Add(-totpPeriod*time.Second - time.Second), false},
+		{"two steps late", stepStart.Add(2 * totpPeriod * time.Second), false},
+	}
+	for _, tt := range tests {
+		got, ok := VerifyTOTP(rfc6238Secret, "081804", tt.at, 0)
+		if ok != tt.want {
+			t.Errorf("%s: accepted = %v, want %v", tt.name, ok, tt.want)
+		}
+		if ok && got != step {
+			t.Errorf("%s: matched step %d, want %d", tt.name, got, step)
+		}
+	}
+}
+
+func TestVerifyTOTPReplay(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+	step, ok := VerifyTOTP(rfc6238Secret, "081804", at, 0)
+	if !ok {
+		t.Fatal("first use refused")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, "081804", at, step); ok {
+		t.Error("code accepted again after its step was used")
+	}
+	// The previous step's code is still within the skew but older than the used step.
+	previous := totpCode(mustKey(t), step-1)
+	if _, ok := VerifyTOTP(rfc6238Secret, previous, at, step); ok {
+		t.Error("code of an earlier step accepted after a later step was used")
+	}
+	// The next step's code is newer, so it is still accepted.
+	next := totpCode(mustKey(t), step+1)
+	if got, ok := VerifyTOTP(rfc6238Secret, next, at, step); !ok || got != step+1 {
+		t.Errorf("code of the next step: got step %d, %v; want %d, true", got, ok, step+1)
+	}
+}
+
+func mustKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes (%v), want 20", secret, len(key), err)
+	}
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+	if _, ok := VerifyTOTP(secret, code, time.Now(), 0); !ok {
+		t.Error("current code of a new secret refused")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	saved := sealKey
+	t.Cleanup(func() { sealKey = saved })
+
+	InitSealKey("test key material")
+	a, err := Seal(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Seal(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("sealing twice gave the same value; the nonce is not random")
+	}
+	for _, sealed := range []string{a, b} {
+		if got, err := Open(sealed); err != nil || got != rfc6238Secret {
+			t.Errorf("Open = %q, %v; want %q", got, err, rfc6238Secret)
+		}
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(a)
+	raw[len(raw)-1] ^= 1
+	if _, err := Open(base64.StdEncoding.EncodeToString(raw)); err == nil {
+		t.Error("tampered value opened")
+	}
+	if _, err := Open("not base64!"); err == nil {
+		t.Error("malformed value opened")
+	}
+	if _, err := Open(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("value shorter than a nonce opened")
+	}
+
+	InitSealKey("other key material")
+	if _, err := Open(a); err == nil {
+		t.Error("value opened under another key")
+	}
+
+	sealKey = nil
+	if _, err := Seal("x"); err == nil {
+		t.Error("Seal worked without a key")
+	}
+}
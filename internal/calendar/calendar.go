@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// lookback bounds how far before a draw date entries are loaded, and so how
+// many consecutive skipped draw days a single window can absorb.
+const lookback = 60
+
+// drawCutoffHour is the hour (draw-date local time) at which a draw window closes.
+const drawCutoffHour = 17
+
+// Calendar is an in-memory view of the calendar entries around a draw date.
+type Calendar struct {
+	entries []models.CalendarEntry
+}
+
+// Load fetches every calendar entry that touches the lookback period ending on date.
+func Load(db *gorm.DB, date time.Time) (*Calendar, error) {
+	day := dayOf(date)
+	from := day.AddDate(0, 0, -lookback)
+	var entries []models.CalendarEntry
+	if err := db.Where("start_date <= ? AND end_date >= ?", day, from).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return &Calendar{entries: entries}, nil
+}
+
+// New builds a Calendar from already-loaded entries.
+func New(entries []models.CalendarEntry) *Calendar {
+	return &Calendar{entries: entries}
+}
+
+// NoDrawEntry returns the holiday or blackout entry covering date, if any.
+func (c *Calendar) NoDrawEntry(date time.Time) (*models.CalendarEntry, bool) {
+	day := dayOf(date)
+	for i, e := range c.entries {
+		if e.Type != models.CalendarHoliday && e.Type != models.CalendarBlackout {
+			continue
+		}
+		if covers(e, day) {
+			return &c.entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// ExtraDrawEntry returns the one-off extra draw scheduled on date, if any.
+func (c *Calendar) ExtraDrawEntry(date time.Time) (*models.CalendarEntry, bool) {
+	day := dayOf(date)
+	for i, e := range c.entries {
+		if e.Type == models.CalendarExtraDraw && covers(e, day) {
+			return &c.entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// DrawWindow returns the entry window for a draw on drawDate.
+//
+// The base rule is unchanged: a Monday draw covers the weekend, a Saturday
+// draw covers the whole week and every other draw covers the previous day.
+// When the previous draw day was a holiday or blackout, the window keeps
+// rolling back until it reaches a day on which a draw could run, so a
+// Tuesday draw after a Monday holiday covers Friday 17:00 to Tuesday 17:00.
+// Extra draws inside a daily window close the preceding window early.
+func (c *Calendar) DrawWindow(drawDate time.Time) (time.Time, time.Time) {
+	day := dayOf(drawDate)
+	windowEnd := time.Date(drawDate.Year(), drawDate.Month(), drawDate.Day(), drawCutoffHour, 0, 0, 0, drawDate.Location())
+
+	prev := previousDrawDay(day)
+	for i := 0; i < lookback; i++ {
+		if _, skipped := c.NoDrawEntry(prev); !skipped {
+			break
+		}
+		prev = previousDrawDay(prev)
+	}
+
+	if day.Weekday() != time.Saturday {
+		for d := prev.AddDate(0, 0, 1); d.Before(day); d = d.AddDate(0, 0, 1) {
+			if _, ok := c.ExtraDrawEntry(d); ok {
+				prev = d
+			}
+		}
+	}
+
+	daysBack := int(day.Sub(prev).Hours() / 24)
+	windowStart := windowEnd.AddDate(0, 0, -daysBack).Add(time.Second)
+	return windowStart, windowEnd
+}
+
+func previousDrawDay(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Monday:
+		return day.AddDate(0, 0, -3)
+	case time.Saturday:
+		return day.AddDate(0, 0, -7)
+	default:
+		return day.AddDate(0, 0, -1)
+	}
+}
+
+func covers(e models.CalendarEntry, day time.Time) bool {
+	return !day.Before(dayOf(e.StartDate)) && !day.After(dayOf(e.EndDate))
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
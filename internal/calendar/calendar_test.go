@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func at(s string, hour, sec int) time.Time {
+	d := date(s)
+	return time.Date(d.Year(), d.Month(), d.Day(), hour, 0, sec, 0, time.UTC)
+}
+
+func entry(typ models.CalendarEntryType, start, end string) models.CalendarEntry {
+	return models.CalendarEntry{Type: typ, Name: string(typ), StartDate: date(start), EndDate: date(end)}
+}
+
+// In 2025, 30 May is a Friday, 31 May a Saturday, 2 June a Monday and 7 June a Saturday.
+func TestDrawWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   []models.CalendarEntry
+		drawDate  string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "tuesday covers monday",
+			drawDate:  "2025-06-03",
+			wantStart: at("2025-06-02", 17, 1),
+			wantEnd:   at("2025-06-03", 17, 0),
+		},
+		{
+			name:      "monday covers the weekend",
+			drawDate:  "2025-06-02",
+			wantStart: at("2025-05-30", 17, 1),
+			wantEnd:   at("2025-06-02", 17, 0),
+		},
+		{
+			name:      "saturday covers the week",
+			drawDate:  "2025-06-07",
+			wantStart: at("2025-05-31", 17, 1),
+			wantEnd:   at("2025-06-07", 17, 0),
+		},
+		{
+			name:      "monday holiday rolls tuesday back to friday",
+			entries:   []models.CalendarEntry{entry(models.CalendarHoliday, "2025-06-02", "2025-06-02")},
+			drawDate:  "2025-06-03",
+			wantStart: at("2025-05-30", 17, 1),
+			wantEnd:   at("2025-06-03", 17, 0),
+		},
+		{
+			name:      "multi-day blackout rolls back past every blacked-out day",
+			entries:   []models.CalendarEntry{entry(models.CalendarBlackout, "2025-06-04", "2025-06-05")},
+			drawDate:  "2025-06-06",
+			wantStart: at("2025-06-03", 17, 1),
+			wantEnd:   at("2025-06-06", 17, 0),
+		},
+		{
+			name:      "blackout ending before the previous draw day changes nothing",
+			entries:   []models.CalendarEntry{entry(models.CalendarBlackout, "2025-06-01", "2025-06-01")},
+			drawDate:  "2025-06-03",
+			wantStart: at("2025-06-02", 17, 1),
+			wantEnd:   at("2025-06-03", 17, 0),
+		},
+		{
+			name:      "extra draw on sunday shortens monday's window",
+			entries:   []models.CalendarEntry{entry(models.CalendarExtraDraw, "2025-06-01", "2025-06-01")},
+			drawDate:  "2025-06-02",
+			wantStart: at("2025-06-01", 17, 1),
+			wantEnd:   at("2025-06-02", 17, 0),
+		},
+		{
+			name: "extra draw after a holiday closes the rolled-back window",
+			entries: []models.CalendarEntry{
+				entry(models.CalendarHoliday, "2025-06-02", "2025-06-02"),
+				entry(models.CalendarExtraDraw, "2025-05-31", "2025-05-31"),
+			},
+			drawDate:  "2025-06-03",
+			wantStart: at("2025-05-31", 17, 1),
+			wantEnd:   at("2025-06-03", 17, 0),
+		},
+		{
+			name:      "saturday window ignores extra draws",
+			entries:   []models.CalendarEntry{entry(models.CalendarExtraDraw, "2025-06-04", "2025-06-04")},
+			drawDate:  "2025-06-07",
+			wantStart: at("2025-05-31", 17, 1),
+			wantEnd:   at("2025-06-07", 17, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := New(tt.entries).DrawWindow(date(tt.drawDate))
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("DrawWindow(%s) = %s to %s, want %s to %s", tt.drawDate, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestNoDrawEntry(t *testing.T) {
+	cal := New([]models.CalendarEntry{
+		entry(models.CalendarBlackout, "2025-06-04", "2025-06-06"),
+		entry(models.CalendarExtraDraw, "2025-06-08", "2025-06-08"),
+	})
+	tests := []struct {
+		day  string
+		want bool
+	}{
+		{"2025-06-03", false},
+		{"2025-06-04", true},
+		{"2025-06-06", true},
+		{"2025-06-07", false},
+		{"2025-06-08", false},
+	}
+	for _, tt := range tests {
+		if _, got := cal.NoDrawEntry(date(tt.day)); got != tt.want {
+			t.Errorf("NoDrawEntry(%s) = %v, want %v", tt.day, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -27,6 +28,35 @@ type AppConfig struct {
 	FrontendURL     string
 	PosthogAPIKey   string // This field is restored
 	PosthogEndpoint string // This field is restored
+	// RNGReportKey is a base64 Ed25519 seed used to sign RNG certification reports.
+	RNGReportKey string
+	// IngestHMACSecret signs recharge batches pushed to /ingest/recharges.
+	IngestHMACSecret string
+	// IngestDropDir is the folder watched for entry files; empty disables the watcher.
+	IngestDropDir string
+	// IngestPollInterval is how often IngestDropDir is scanned.
+	IngestPollInterval time.Duration
+	// AccessTokenTTL bounds access tokens; RefreshTokenTTL bounds a session
+	// between refreshes.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// MFAEnforce makes TOTP enrolment mandatory for roles granting a sensitive permission.
+	MFAEnforce bool
+	// MFAIssuer names the account in authenticator apps.
+	MFAIssuer string
+	// MFASecretKey encrypts stored TOTP secrets; it defaults to JWTSecret.
+	MFASecretKey string
+	// SMTP relay for outgoing mail. SMTPHost is required unless MailFake is set.
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	// MailFake keeps mail in memory instead of sending it, for development only.
+	MailFake bool
+	// DrawTrace records every random pick of a draw for the trace endpoint.
+	// It is on unless DRAW_TRACE is set to false.
+	DrawTrace bool
 }
 
 // Load reads environment variables (and .env if present)
@@ -34,17 +64,57 @@ func Load() *AppConfig {
 	_ = godotenv.Load()
 
 	Cfg = &AppConfig{
-		Port:            os.Getenv("PORT"),
-		DBHost:          os.Getenv("DB_HOST"),
-		DBPort:          os.Getenv("DB_PORT"),
-		DBUser:          os.Getenv("DB_USER"),
-		DBName:          os.Getenv("DB_NAME"),
-		DBPassword:      os.Getenv("DB_PASSWORD"),
-		DBSSLMode:       os.Getenv("DB_SSLMODE"),
-		JWTSecret:       os.Getenv("JWT_SECRET_KEY"),
-		FrontendURL:     os.Getenv("FRONTEND_URL"),
-		PosthogAPIKey:   os.Getenv("POSTHOG_API_KEY"),           // This line is restored
-		PosthogEndpoint: os.Getenv("POSTHOG_INSTANCE_ADDRESS"), // This line is restored
+		Port:             os.Getenv("PORT"),
+		DBHost:           os.Getenv("DB_HOST"),
+		DBPort:           os.Getenv("DB_PORT"),
+		DBUser:           os.Getenv("DB_USER"),
+		DBName:           os.Getenv("DB_NAME"),
+		DBPassword:       os.Getenv("DB_PASSWORD"),
+		DBSSLMode:        os.Getenv("DB_SSLMODE"),
+		JWTSecret:        os.Getenv("JWT_SECRET_KEY"),
+		FrontendURL:      os.Getenv("FRONTEND_URL"),
+		PosthogAPIKey:    os.Getenv("POSTHOG_API_KEY"),          // This line is restored
+		PosthogEndpoint:  os.Getenv("POSTHOG_INSTANCE_ADDRESS"), // This line is restored
+		RNGReportKey:     os.Getenv("RNG_REPORT_SIGNING_KEY"),
+		IngestHMACSecret: os.Getenv("INGEST_HMAC_SECRET"),
+		IngestDropDir:    os.Getenv("INGEST_DROP_DIR"),
+		MFAIssuer:        os.Getenv("MFA_ISSUER"),
+		MFASecretKey:     os.Getenv("MFA_SECRET_KEY"),
+		SMTPHost:         os.Getenv("SMTP_HOST"),
+		SMTPPort:         os.Getenv("SMTP_PORT"),
+		SMTPUsername:     os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		MailFrom:         os.Getenv("MAIL_FROM"),
+	}
+	Cfg.MFAEnforce, _ = strconv.ParseBool(os.Getenv("MFA_ENFORCE"))
+	Cfg.MailFake, _ = strconv.ParseBool(os.Getenv("MAIL_FAKE"))
+	Cfg.DrawTrace = true
+	if trace, err := strconv.ParseBool(os.Getenv("DRAW_TRACE")); err == nil {
+		Cfg.DrawTrace = trace
+	}
+	if Cfg.MFAIssuer == "" {
+		Cfg.MFAIssuer = "Promo Admin"
+	}
+	if Cfg.MFASecretKey == "" {
+		Cfg.MFASecretKey = Cfg.JWTSecret
+	}
+	Cfg.IngestPollInterval = 30 * time.Second
+	if secs, err := strconv.Atoi(os.Getenv("INGEST_POLL_SECONDS")); err == nil && secs > 0 {
+		Cfg.IngestPollInterval = time.Duration(secs) * time.Second
+	}
+	Cfg.AccessTokenTTL = 15 * time.Minute
+	if mins, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_MINUTES")); err == nil && mins > 0 {
+		Cfg.AccessTokenTTL = time.Duration(mins) * time.Minute
+	}
+	Cfg.RefreshTokenTTL = 7 * 24 * time.Hour
+	if hours, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_HOURS")); err == nil && hours > 0 {
+		Cfg.RefreshTokenTTL = time.Duration(hours) * time.Hour
+	}
+	if Cfg.SMTPPort == "" {
+		Cfg.SMTPPort = "587"
+	}
+	if Cfg.MailFrom == "" {
+		Cfg.MailFrom = "no-reply@localhost"
 	}
 	if Cfg.Port == "" {
 		Cfg.Port = "8080"
@@ -82,4 +152,4 @@ func InitDB(c *AppConfig) *gorm.DB {
 	}
 	DB = db
 	return db
-}
\ No newline at end of file
+}
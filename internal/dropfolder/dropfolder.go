@@ -0,0 +1,196 @@
+// Package dropfolder loads entry files dropped into a watched directory into
+// the recharge ledger.
+//
+// A file is claimed by renaming it into processing/, which is atomic, so a
+// file is loaded by one ingester only. Every file becomes a FILE ingestion job
+// carrying its SHA-256 checksum, row counts and, when its name contains a date,
+// the draw date it was delivered for. A file loads completely or not at all:
+// any invalid row fails the file, which is then moved to failed/ next to an
+// .errors.txt report; loaded files go to processed/.
+package dropfolder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/calendar"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Subdirectories of the watched directory.
+const (
+	processingDir = "processing"
+	processedDir  = "processed"
+	failedDir     = "failed"
+)
+
+// settleTime is how long a file must go unmodified before it is picked up, so
+// files still being copied in are left alone.
+const settleTime = 10 * time.Second
+
+// Ingester watches Dir for entry files.
+type Ingester struct {
+	DB       *gorm.DB
+	Dir      string
+	Interval time.Duration
+}
+
+// New returns an ingester for dir that scans every interval.
+func New(db *gorm.DB, dir string, interval time.Duration) *Ingester {
+	return &Ingester{DB: db, Dir: dir, Interval: interval}
+}
+
+// Run creates the working folders, returns files left in processing/ by a
+// previous run to the drop folder and scans until ctx is cancelled.
+func (in *Ingester) Run(ctx context.Context) error {
+	for _, sub := range []string{processingDir, processedDir, failedDir} {
+		if err := os.MkdirAll(filepath.Join(in.Dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+	stale, err := os.ReadDir(filepath.Join(in.Dir, processingDir))
+	if err != nil {
+		return err
+	}
+	for _, f := range stale {
+		if err := os.Rename(filepath.Join(in.Dir, processingDir, f.Name()), filepath.Join(in.Dir, f.Name())); err != nil {
+			log.Printf("dropfolder: requeueing %s: %v", f.Name(), err)
+		}
+	}
+
+	ticker := time.NewTicker(in.Interval)
+	defer ticker.Stop()
+	for {
+		in.Scan()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// eligible reports whether a directory entry looks like a finished entry file.
+func eligible(f os.DirEntry, now time.Time) bool {
+	name := strings.ToLower(f.Name())
+	if !f.Type().IsRegular() || strings.HasPrefix(name, ".") {
+		return false
+	}
+	if !strings.HasSuffix(name, ".csv") && !strings.HasSuffix(name, ".gz") {
+		return false
+	}
+	info, err := f.Info()
+	return err == nil && now.Sub(info.ModTime()) >= settleTime
+}
+
+// Scan processes every eligible file currently in the drop folder.
+func (in *Ingester) Scan() {
+	files, err := os.ReadDir(in.Dir)
+	if err != nil {
+		log.Printf("dropfolder: listing %s: %v", in.Dir, err)
+		return
+	}
+	now := time.Now()
+	for _, f := range files {
+		if !eligible(f, now) {
+			continue
+		}
+		claimed := filepath.Join(in.Dir, processingDir, f.Name())
+		if err := os.Rename(filepath.Join(in.Dir, f.Name()), claimed); err != nil {
+			continue // another ingester took it, or it vanished
+		}
+		if err := in.process(claimed); err != nil {
+			log.Printf("dropfolder: %s: %v", f.Name(), err)
+		}
+	}
+}
+
+// process loads one claimed file and files it under processed/ or failed/.
+func (in *Ingester) process(path string) error {
+	name := filepath.Base(path)
+	job, err := ledger.StartJob(in.DB, ledger.SourceFile, name)
+	if err != nil {
+		return err // leave it in processing/ for the next start
+	}
+	job.DrawDate = ledger.DrawDateFromName(name)
+
+	rowErrors, loadErr := in.load(job, path)
+	if ferr := ledger.FinishJob(in.DB, job, loadErr); ferr != nil {
+		log.Printf("dropfolder: saving job %s: %v", job.ID, ferr)
+	}
+
+	stamp := time.Now().Format("20060102T150405")
+	if loadErr != nil {
+		dest := filepath.Join(in.Dir, failedDir, stamp+"_"+name)
+		report := loadErr.Error() + "\n" + strings.Join(rowErrors, "\n") + "\n"
+		if err := os.WriteFile(dest+".errors.txt", []byte(report), 0o644); err != nil {
+			log.Printf("dropfolder: writing error report for %s: %v", name, err)
+		}
+		if err := os.Rename(path, dest); err != nil {
+			return err
+		}
+		return loadErr
+	}
+	return os.Rename(path, filepath.Join(in.Dir, processedDir, stamp+"_"+name))
+}
+
+// fileChecksum returns the hex SHA-256 of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// load streams the file into the ledger under job in one transaction. It
+// returns the row errors found, if any.
+func (in *Ingester) load(job *models.IngestionJob, path string) ([]string, error) {
+	// The checksum keys rows without an external_id, so it is needed up front;
+	// checking it here also skips streaming a file that was already loaded.
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	job.Checksum = sum
+	if earlier, err := ledger.LoadedChecksum(in.DB, sum, job); err != nil {
+		return nil, err
+	} else if earlier != nil {
+		return nil, fmt.Errorf("identical to %s, already loaded by job %s", earlier.Reference, earlier.ID)
+	}
+
+	// Rows without a timestamp belong to the end of the draw date's entry window.
+	var defaultTime time.Time
+	if job.DrawDate != nil {
+		cal, err := calendar.Load(in.DB, *job.DrawDate)
+		if err != nil {
+			return nil, err
+		}
+		_, defaultTime = cal.DrawWindow(*job.DrawDate)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		job.BytesTotal = info.Size()
+	}
+	return ledger.LoadCSV(context.Background(), in.DB, job, f, sum, defaultTime)
+}
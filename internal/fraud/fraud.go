@@ -0,0 +1,321 @@
+// Package fraud screens draw pools for MSISDNs that look like SIM farms or
+// recharge cycling. Each check flags MSISDNs on its own; what happens to a
+// flagged entry, exclusion from the pool or review after the draw, is decided
+// by the caller from the configured mode.
+package fraud
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Rules a flag can record.
+const (
+	RuleSequentialBlock  = "SEQUENTIAL_BLOCK"
+	RuleIdenticalPattern = "IDENTICAL_PATTERN"
+	RuleFrequency        = "ABNORMAL_FREQUENCY"
+	RuleExcessivePoints  = "EXCESSIVE_POINTS"
+)
+
+// historyBatchSize bounds the MSISDNs looked up in one history query.
+const historyBatchSize = 1000
+
+// Defaults are the settings used until a SUPERADMIN saves their own.
+var Defaults = models.FraudSettings{
+	Enabled:             true,
+	Mode:                models.FraudModeHold,
+	SequentialBlockMin:  8,
+	PatternMinNumbers:   5,
+	PatternMinEvents:    3,
+	MaxRechargesPerDay:  30,
+	HistoryFactor:       10,
+	HistoryMinPoints:    500,
+	HistoryLookbackDays: 30,
+}
+
+// Validate reports settings that cannot be applied.
+func Validate(s models.FraudSettings) error {
+	if s.Mode != models.FraudModeExclude && s.Mode != models.FraudModeHold {
+		return fmt.Errorf("mode must be %s or %s", models.FraudModeExclude, models.FraudModeHold)
+	}
+	if s.SequentialBlockMin < 0 || s.PatternMinNumbers < 0 || s.PatternMinEvents < 0 || s.MaxRechargesPerDay < 0 ||
+		s.HistoryFactor < 0 || s.HistoryMinPoints < 0 || s.HistoryLookbackDays < 0 {
+		return errors.New("thresholds cannot be negative")
+	}
+	if s.SequentialBlockMin == 1 || s.PatternMinNumbers == 1 {
+		return errors.New("sequential_block_min and pattern_min_numbers must be 0 (off) or at least 2")
+	}
+	if s.HistoryFactor > 0 && s.HistoryLookbackDays == 0 {
+		return errors.New("history_lookback_days is required when history_factor is set")
+	}
+	return nil
+}
+
+// Load returns the stored settings, or Defaults when none are stored.
+func Load(db *gorm.DB) (models.FraudSettings, error) {
+	var s models.FraudSettings
+	err := db.Order("updated_at desc").First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Defaults, nil
+	}
+	return s, err
+}
+
+// Flag is one rule one MSISDN tripped.
+type Flag struct {
+	MSISDN string `json:"msisdn"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+	Points int    `json:"points"`
+}
+
+// Pool is what the screen looks at. Events selects the recharge events behind
+// the entries and is nil for pools uploaded without events, which skips the
+// event-based checks. WindowStart and WindowEnd are the draw's entry window;
+// history is read from before WindowStart.
+type Pool struct {
+	Entries                []models.EligibleEntry
+	Events                 *gorm.DB
+	WindowStart, WindowEnd time.Time
+}
+
+// Screen runs every enabled check over pool and returns the flags, ordered by
+// MSISDN and rule. Flags are only raised for MSISDNs in pool.Entries.
+func Screen(db *gorm.DB, s models.FraudSettings, pool Pool) ([]Flag, error) {
+	if !s.Enabled || len(pool.Entries) == 0 {
+		return nil, nil
+	}
+	points := make(map[string]int, len(pool.Entries))
+	for _, e := range pool.Entries {
+		points[e.MSISDN] = e.Points
+	}
+
+	var flags []Flag
+	add := func(found []Flag) {
+		for _, f := range found {
+			if p, ok := points[f.MSISDN]; ok {
+				f.Points = p
+				flags = append(flags, f)
+			}
+		}
+	}
+	if s.SequentialBlockMin > 0 {
+		add(sequentialBlocks(pool.Entries, s.SequentialBlockMin))
+	}
+	if pool.Events != nil && s.PatternMinNumbers > 0 {
+		found, err := identicalPatterns(db, pool.Events, s.PatternMinNumbers, s.PatternMinEvents)
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+	}
+	if pool.Events != nil && s.MaxRechargesPerDay > 0 {
+		found, err := abnormalFrequency(db, pool.Events, s.MaxRechargesPerDay)
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+	}
+	if s.HistoryFactor > 0 {
+		found, err := excessivePoints(db, pool, s)
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		if flags[i].MSISDN != flags[j].MSISDN {
+			return flags[i].MSISDN < flags[j].MSISDN
+		}
+		return flags[i].Rule < flags[j].Rule
+	})
+	return flags, nil
+}
+
+// sequentialBlocks flags runs of consecutive numbers, the signature of SIM
+// cards bought in bulk. minRun is the shortest run flagged; dense pools, where
+// honest subscribers hold neighbouring numbers too, need longer runs (see
+// chanceRunMin).
+func sequentialBlocks(entries []models.EligibleEntry, minRun int) []Flag {
+	type number struct {
+		n      uint64
+		msisdn string
+	}
+	numbers := make([]number, 0, len(entries))
+	for _, e := range entries {
+		if n, err := strconv.ParseUint(e.MSISDN, 10, 64); err == nil {
+			numbers = append(numbers, number{n, e.MSISDN})
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i].n < numbers[j].n })
+	neighbours := 0
+	for i := 1; i < len(numbers); i++ {
+		if numbers[i].n == numbers[i-1].n+1 {
+			neighbours++
+		}
+	}
+	minRun = chanceRunMin(len(numbers), neighbours, minRun)
+
+	var flags []Flag
+	for start := 0; start < len(numbers); {
+		end := start + 1
+		for end < len(numbers) && numbers[end].n == numbers[end-1].n+1 {
+			end++
+		}
+		if run := end - start; run >= minRun {
+			detail := fmt.Sprintf("one of %d consecutive numbers %s to %s", run, numbers[start].msisdn, numbers[end-1].msisdn)
+			for _, num := range numbers[start:end] {
+				flags = append(flags, Flag{MSISDN: num.msisdn, Rule: RuleSequentialBlock, Detail: detail})
+			}
+		}
+		start = end
+	}
+	return flags
+}
+
+// chanceRuns is how many runs of the length sequentialBlocks requires may be
+// expected in a pool by chance alone.
+const chanceRuns = 0.01
+
+// chanceRunMin returns the shortest run, at least minRun, that n sorted
+// numbers form by chance no more than chanceRuns times on average. The share
+// p of neighbouring pairs that are consecutive estimates how densely the pool
+// fills its number ranges; a run of k then starts at a given number with
+// probability about p^(k-1), so n*p^(k-1) runs of k are expected.
+func chanceRunMin(n, neighbours, minRun int) int {
+	if n < 2 || neighbours == 0 || neighbours == n-1 {
+		return minRun
+	}
+	p := float64(neighbours) / float64(n-1)
+	k := minRun
+	for float64(n)*math.Pow(p, float64(k-1)) >= chanceRuns {
+		k++
+	}
+	return k
+}
+
+// identicalPatterns flags groups of at least minNumbers MSISDNs whose
+// recharges, at least minEvents each, match in amount and minute.
+func identicalPatterns(db *gorm.DB, events *gorm.DB, minNumbers, minEvents int) ([]Flag, error) {
+	var rows []struct {
+		MSISDN  string
+		Events  int
+		Numbers int
+	}
+	err := db.Raw(`
+		SELECT msisdn, events, numbers FROM (
+			SELECT msisdn, events, COUNT(*) OVER (PARTITION BY signature) AS numbers FROM (
+				SELECT msisdn, COUNT(*) AS events,
+					md5(string_agg(amount_kobo::text || '@' || to_char(occurred_at AT TIME ZONE 'Africa/Lagos', 'YYYY-MM-DD HH24:MI'), ',' ORDER BY occurred_at, amount_kobo)) AS signature
+				FROM (?) e
+				GROUP BY msisdn
+				HAVING COUNT(*) >= ?
+			) s
+		) g
+		WHERE numbers >= ?`, events.Select("msisdn, amount_kobo, occurred_at"), minEvents, minNumbers).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("fraud: recharge patterns: %w", err)
+	}
+	flags := make([]Flag, 0, len(rows))
+	for _, r := range rows {
+		flags = append(flags, Flag{MSISDN: r.MSISDN, Rule: RuleIdenticalPattern,
+			Detail: fmt.Sprintf("%d recharges identical in amount and time to %d other numbers", r.Events, r.Numbers-1)})
+	}
+	return flags, nil
+}
+
+// abnormalFrequency flags MSISDNs with more than maxPerDay recharges on any Lagos day.
+func abnormalFrequency(db *gorm.DB, events *gorm.DB, maxPerDay int) ([]Flag, error) {
+	var rows []struct {
+		MSISDN string
+		Day    time.Time
+		Events int
+	}
+	err := db.Raw(`
+		SELECT DISTINCT ON (msisdn) msisdn, day, events FROM (
+			SELECT msisdn, (occurred_at AT TIME ZONE 'Africa/Lagos')::date AS day, COUNT(*) AS events
+			FROM (?) e
+			GROUP BY 1, 2
+			HAVING COUNT(*) > ?
+		) d
+		ORDER BY msisdn, events DESC`, events.Select("msisdn, occurred_at"), maxPerDay).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("fraud: recharge frequency: %w", err)
+	}
+	flags := make([]Flag, 0, len(rows))
+	for _, r := range rows {
+		flags = append(flags, Flag{MSISDN: r.MSISDN, Rule: RuleFrequency,
+			Detail: fmt.Sprintf("%d recharges on %s, above %d a day", r.Events, r.Day.Format("2006-01-02"), maxPerDay)})
+	}
+	return flags, nil
+}
+
+// excessivePoints flags MSISDNs holding at least HistoryMinPoints whose points
+// exceed HistoryFactor times what they earned over an equal span on average in
+// the HistoryLookbackDays before the window. Numbers with no history are
+// compared against a single point.
+func excessivePoints(db *gorm.DB, pool Pool, s models.FraudSettings) ([]Flag, error) {
+	var candidates []models.EligibleEntry
+	for _, e := range pool.Entries {
+		if e.Points >= s.HistoryMinPoints {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	span := pool.WindowEnd.Sub(pool.WindowStart)
+	if span < 24*time.Hour {
+		span = 24 * time.Hour
+	}
+	lookback := time.Duration(s.HistoryLookbackDays) * 24 * time.Hour
+	from := pool.WindowStart.Add(-lookback)
+
+	var flags []Flag
+	for start := 0; start < len(candidates); start += historyBatchSize {
+		end := start + historyBatchSize
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		batch := candidates[start:end]
+		msisdns := make([]string, len(batch))
+		for i, e := range batch {
+			msisdns[i] = e.MSISDN
+		}
+		var rows []struct {
+			MSISDN string
+			Points int64
+		}
+		err := ledger.RecordedEvents(db).
+			Select("msisdn, SUM(points) AS points").
+			Where("msisdn IN ? AND occurred_at >= ? AND occurred_at < ?", msisdns, from, pool.WindowStart).
+			Group("msisdn").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("fraud: points history: %w", err)
+		}
+		prior := make(map[string]int64, len(rows))
+		for _, r := range rows {
+			prior[r.MSISDN] = r.Points
+		}
+		for _, e := range batch {
+			expected := math.Max(float64(prior[e.MSISDN])*float64(span)/float64(lookback), 1)
+			if float64(e.Points) > float64(s.HistoryFactor)*expected {
+				flags = append(flags, Flag{MSISDN: e.MSISDN, Rule: RuleExcessivePoints,
+					Detail: fmt.Sprintf("%d points against about %.0f expected from the last %d days", e.Points, expected, s.HistoryLookbackDays)})
+			}
+		}
+	}
+	return flags, nil
+}
@@ -0,0 +1,331 @@
+package fraud
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stubDriver answers every query with canned rows, so the SQL-backed checks
+// can be tested without PostgreSQL. The SQL itself is not run: the tests
+// check the thresholds passed to it and how its rows become flags.
+type stubDriver struct{}
+
+type stub struct {
+	columns []string
+	rows    [][]driver.Value
+	args    []any
+}
+
+var (
+	stubsMu sync.Mutex
+	stubs   = map[string]*stub{}
+)
+
+func init() { sql.Register("fraudstub", stubDriver{}) }
+
+func (stubDriver) Open(name string) (driver.Conn, error) {
+	stubsMu.Lock()
+	defer stubsMu.Unlock()
+	s, ok := stubs[name]
+	if !ok {
+		return nil, fmt.Errorf("stub: no stub named %q", name)
+	}
+	return stubConn{s}, nil
+}
+
+type stubConn struct{ s *stub }
+
+func (stubConn) Close() error { return nil }
+
+func (stubConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("stub: prepare not supported")
+}
+
+func (stubConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("stub: transactions not supported")
+}
+
+func (c stubConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	for _, a := range args {
+		c.s.args = append(c.s.args, a.Value)
+	}
+	return &stubRows{columns: c.s.columns, rows: c.s.rows}, nil
+}
+
+type stubRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return r.columns }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// stubDB returns a database whose queries all return rows under columns.
+func stubDB(t *testing.T, columns []string, rows ...[]driver.Value) (*gorm.DB, *stub) {
+	t.Helper()
+	s := &stub{columns: columns, rows: rows}
+	stubsMu.Lock()
+	stubs[t.Name()] = s
+	stubsMu.Unlock()
+	sqlDB, err := sql.Open("fraudstub", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, s
+}
+
+func entries(points int, msisdns ...string) []models.EligibleEntry {
+	out := make([]models.EligibleEntry, len(msisdns))
+	for i, m := range msisdns {
+		out[i] = models.EligibleEntry{MSISDN: m, Points: points}
+	}
+	return out
+}
+
+// block returns n consecutive MSISDNs starting at first.
+func block(first uint64, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprint(first + uint64(i))
+	}
+	return out
+}
+
+func flagged(flags []Flag) []string {
+	out := []string{}
+	for _, f := range flags {
+		out = append(out, f.MSISDN)
+	}
+	return out
+}
+
+// sparse returns n MSISDNs with no neighbours, so a pool padded with them
+// is too sparse for runs to be expected by chance.
+func sparse(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprint(2348100000000 + uint64(i)*1000)
+	}
+	return out
+}
+
+func TestSequentialBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		msisdns []string
+		minRun  int
+		want    []string
+	}{
+		{"run at the minimum", block(2348030000001, 5), 5, block(2348030000001, 5)},
+		{"run below the minimum", block(2348030000001, 4), 5, []string{}},
+		{"unsorted input", []string{"2348030000003", "2348030000005", "2348030000001", "2348030000004", "2348030000002"}, 5, block(2348030000001, 5)},
+		{"two runs", append(block(2348070000001, 5), block(2348030000001, 5)...), 5, append(block(2348030000001, 5), block(2348070000001, 5)...)},
+		{"non-numeric MSISDNs are skipped", append([]string{"x"}, block(2348030000001, 5)...), 5, block(2348030000001, 5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := flagged(sequentialBlocks(entries(1, append(tt.msisdns, sparse(100)...)...), tt.minRun))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flagged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequentialBlocksDensePool(t *testing.T) {
+	// Half the numbers of a range are held at random. Runs of the default
+	// minimum of 8 are expected by chance in a pool this dense, so they must
+	// not be flagged.
+	r := rand.New(rand.NewPCG(1, 2))
+	var msisdns []string
+	run, longest := 0, 0
+	for i := uint64(0); i < 4000; i++ {
+		if r.IntN(2) == 0 {
+			msisdns = append(msisdns, fmt.Sprint(2348030000000+i))
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	if longest < Defaults.SequentialBlockMin {
+		t.Fatalf("longest chance run is %d; the test needs one of at least %d", longest, Defaults.SequentialBlockMin)
+	}
+	if got := sequentialBlocks(entries(1, msisdns...), Defaults.SequentialBlockMin); len(got) != 0 {
+		t.Errorf("flagged %d MSISDNs in a dense pool, want none", len(got))
+	}
+
+	// A SIM farm block stands out from the same pool.
+	farm := block(2348060000000, 30)
+	got := flagged(sequentialBlocks(entries(1, append(msisdns, farm...)...), Defaults.SequentialBlockMin))
+	if !reflect.DeepEqual(got, farm) {
+		t.Errorf("flagged %v, want the 30-number block", got)
+	}
+}
+
+func TestChanceRunMin(t *testing.T) {
+	tests := []struct {
+		n, neighbours, minRun, want int
+	}{
+		{1, 0, 8, 8},
+		{1000, 0, 8, 8},
+		{1000, 999, 8, 8},
+		{1000, 10, 8, 8},
+		// p = 0.5: 1001 * 0.5^(k-1) < 0.01 first holds at k = 18.
+		{1001, 500, 8, 18},
+		// p = 0.9: 101 * 0.9^(k-1) < 0.01 first holds at k = 89.
+		{101, 90, 8, 89},
+	}
+	for _, tt := range tests {
+		if got := chanceRunMin(tt.n, tt.neighbours, tt.minRun); got != tt.want {
+			t.Errorf("chanceRunMin(%d, %d, %d) = %d, want %d", tt.n, tt.neighbours, tt.minRun, got, tt.want)
+		}
+	}
+}
+
+func TestIdenticalPatterns(t *testing.T) {
+	db, s := stubDB(t, []string{"msisdn", "events", "numbers"},
+		[]driver.Value{"2348030000001", int64(4), int64(6)},
+		[]driver.Value{"2348030000002", int64(4), int64(6)},
+	)
+	flags, err := identicalPatterns(db, db.Model(&models.RechargeEvent{}), 6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Flag{
+		{MSISDN: "2348030000001", Rule: RuleIdenticalPattern, Detail: "4 recharges identical in amount and time to 5 other numbers"},
+		{MSISDN: "2348030000002", Rule: RuleIdenticalPattern, Detail: "4 recharges identical in amount and time to 5 other numbers"},
+	}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags = %+v, want %+v", flags, want)
+	}
+	if want := []any{int64(3), int64(6)}; !reflect.DeepEqual(s.args, want) {
+		t.Errorf("query args = %v, want min events then min numbers %v", s.args, want)
+	}
+}
+
+func TestAbnormalFrequency(t *testing.T) {
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	db, s := stubDB(t, []string{"msisdn", "day", "events"},
+		[]driver.Value{"2348030000001", day, int64(45)},
+	)
+	flags, err := abnormalFrequency(db, db.Model(&models.RechargeEvent{}), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Flag{{MSISDN: "2348030000001", Rule: RuleFrequency, Detail: "45 recharges on 2025-06-02, above 30 a day"}}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags = %+v, want %+v", flags, want)
+	}
+	if want := []any{int64(30)}; !reflect.DeepEqual(s.args, want) {
+		t.Errorf("query args = %v, want %v", s.args, want)
+	}
+}
+
+func TestExcessivePoints(t *testing.T) {
+	// Over 30 days of history, 1800 points is about 60 for a one-day window.
+	db, s := stubDB(t, []string{"msisdn", "points"},
+		[]driver.Value{"2348030000002", int64(1800)},
+		[]driver.Value{"2348030000003", int64(1800)},
+	)
+	start := time.Date(2025, 6, 2, 16, 0, 1, 0, time.UTC)
+	pool := Pool{
+		Entries: []models.EligibleEntry{
+			{MSISDN: "2348030000001", Points: 600}, // no history: against 1 point
+			{MSISDN: "2348030000002", Points: 600}, // exactly 10x expected
+			{MSISDN: "2348030000003", Points: 601},
+			{MSISDN: "2348030000004", Points: 400}, // below HistoryMinPoints
+		},
+		WindowStart: start,
+		WindowEnd:   start.Add(24*time.Hour - time.Second),
+	}
+	settings := models.FraudSettings{HistoryFactor: 10, HistoryMinPoints: 500, HistoryLookbackDays: 30}
+	flags, err := excessivePoints(db, pool, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := flagged(flags), []string{"2348030000001", "2348030000003"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+	for _, a := range s.args {
+		if a == "2348030000004" {
+			t.Errorf("history was read for an MSISDN below history_min_points")
+		}
+	}
+	if len(flags) > 0 && !strings.Contains(flags[0].Detail, "about 1 expected") {
+		t.Errorf("detail %q does not report the one-point floor", flags[0].Detail)
+	}
+}
+
+func TestScreen(t *testing.T) {
+	pool := Pool{Entries: entries(5, sparse(100)...)}
+	for i, m := range block(2348030000001, 5) {
+		pool.Entries = append(pool.Entries, models.EligibleEntry{MSISDN: m, Points: 10 * (i + 1)})
+	}
+	settings := models.FraudSettings{Enabled: true, Mode: models.FraudModeHold, SequentialBlockMin: 5}
+
+	flags, err := Screen(nil, settings, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := flagged(flags), block(2348030000001, 5); !reflect.DeepEqual(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	for i, f := range flags {
+		if f.Points != 10*(i+1) {
+			t.Errorf("flag for %s carries %d points, want %d", f.MSISDN, f.Points, 10*(i+1))
+		}
+	}
+
+	settings.Enabled = false
+	if flags, _ := Screen(nil, settings, pool); len(flags) != 0 {
+		t.Errorf("disabled screen raised %d flags", len(flags))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(Defaults); err != nil {
+		t.Errorf("Defaults: %v", err)
+	}
+	bad := []models.FraudSettings{
+		{Mode: "WARN"},
+		{Mode: models.FraudModeHold, SequentialBlockMin: 1},
+		{Mode: models.FraudModeHold, PatternMinNumbers: 1},
+		{Mode: models.FraudModeHold, MaxRechargesPerDay: -1},
+		{Mode: models.FraudModeHold, HistoryFactor: 10},
+	}
+	for _, s := range bad {
+		if err := Validate(s); err == nil {
+			t.Errorf("Validate(%+v) accepted invalid settings", s)
+		}
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"github.com/ArowuTest/promo-backend/internal/auth"
 	"github.com/ArowuTest/promo-backend/internal/config"
 	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rbac"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -20,7 +23,11 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// Login authenticates an admin user and returns a JWT.
+// Login authenticates an admin user and opens a session, returning a
+// short-lived access token and a refresh token. Failed attempts lock the user
+// and the client IP out for a while; Inactive and Locked users are refused.
+// Users with MFA get a challenge for LoginMFA instead; users who must enrol
+// but have not get a session that can only reach the /auth routes.
 func Login(c *gin.Context) {
 	var req loginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -28,11 +35,26 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Guesses from a locked-out IP are not checked at all.
+	wait, err := lockedFor(config.DB, ipThrottleKey(c.ClientIP()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if wait > 0 {
+		refuseLocked(c, wait)
+		return
+	}
+
 	// Look up the user by username
 	var user models.AdminUser
 	if err := config.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		// Not found or DB error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := recordLoginFailure(c, nil); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -40,61 +62,169 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	wait, err = lockedFor(config.DB, userThrottleKey(user.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if wait > 0 {
+		refuseLocked(c, wait)
+		return
+	}
+
 	// Compare hashed password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		if err := recordLoginFailure(c, &user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	// 24‐hour token
-	token, err := auth.GenerateJWT(user.ID.String(), user.Username, string(user.Role), 24*time.Hour)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	// Status is only revealed to someone who knows the password.
+	switch user.Status {
+	case models.StatusActive:
+	case models.StatusLocked:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is locked; ask a SUPERADMIN to unlock it"})
+		return
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active"})
+		return
+	}
+	// Failures are cleared once the login is complete: here, or by LoginMFA
+	// once the second factor is accepted.
+	if user.MFAEnabled {
+		body, err := startMFAChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login challenge"})
+			return
+		}
+		c.JSON(http.StatusOK, body)
+		return
+	}
+	if err := clearUserFailures(config.DB, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":    token,
-		"user_id":  user.ID.String(),
-		"username": user.Username,
-		"role":     user.Role,
-	})
+	body, err := startSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+	perms, err := rbac.Load(config.DB, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if mfaEnrolmentRequired(perms) {
+		body["mfa_enrolment_required"] = true
+	}
+	c.JSON(http.StatusOK, body)
 }
 
-// RequireAuth checks for a valid “Bearer <token>” header and optional role restriction.
-func RequireAuth(allowedRoles ...models.AdminUserRole) gin.HandlerFunc {
+// RequireAuth checks for a valid “Bearer <token>” header. The token's session
+// must not be revoked and its user must still be Active. When an earlier
+// RequireAuth in the chain has authenticated the request it passes straight
+// through. Users who must enrol in MFA are held to the /auth routes until they do.
+func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		h := c.GetHeader("Authorization")
-		if h == "" || !strings.HasPrefix(h, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
-			return
+		if _, done := c.Get("session_id"); !done {
+			if !authenticate(c) {
+				return
+			}
 		}
-		tokenStr := strings.TrimPrefix(h, "Bearer ")
-		claims, err := auth.ParseAndVerify(tokenStr)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if c.GetBool("mfa_enrolment_required") && !strings.HasPrefix(c.FullPath(), "/api/v1/auth/") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "MFA enrolment required", "mfa_enrolment_required": true})
 			return
 		}
+		c.Next()
+	}
+}
 
-		// If allowedRoles is non‐empty, check that claims.Role is in allowedRoles
-		if len(allowedRoles) > 0 {
-			valid := false
-			for _, r := range allowedRoles {
-				if string(r) == claims.Role {
-					valid = true
-					break
-				}
-			}
-			if !valid {
-				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+// RequirePermission authenticates like RequireAuth and then requires every one
+// of perms. Permissions come from the user's current role, not the token.
+func RequirePermission(perms ...rbac.Permission) gin.HandlerFunc {
+	authn := RequireAuth()
+	return func(c *gin.Context) {
+		if _, done := c.Get("session_id"); !done {
+			authn(c)
+			if c.IsAborted() {
 				return
 			}
 		}
+		if !permissions(c).Has(perms...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden", "required_permissions": perms})
+			return
+		}
+		c.Next()
+	}
+}
 
-		// Store user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_role", claims.Role)
+// permissions returns the authenticated user's permissions.
+func permissions(c *gin.Context) rbac.Set {
+	perms, _ := c.Get("user_permissions")
+	set, _ := perms.(rbac.Set)
+	return set
+}
 
-		c.Next()
+// authenticate verifies the bearer token and its session and stores the user
+// in the context. It aborts the request and returns false on failure.
+func authenticate(c *gin.Context) bool {
+	h := c.GetHeader("Authorization")
+	if h == "" || !strings.HasPrefix(h, "Bearer ") {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
+		return false
+	}
+	tokenStr := strings.TrimPrefix(h, "Bearer ")
+	claims, err := auth.ParseAndVerify(tokenStr)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return false
+	}
+	// Tokens issued before sessions existed carry no session and are refused.
+	if _, err := uuid.Parse(claims.SessionID); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return false
+	}
+
+	var row struct {
+		RevokedAt   *time.Time
+		Status      models.UserStatus
+		Role        models.AdminUserRole
+		MFAEnabled  bool
+		Permissions pq.StringArray
+	}
+	err = config.DB.Table("admin_sessions AS s").
+		Select("s.revoked_at, u.status, u.role, u.mfa_enabled, r.permissions").
+		Joins("JOIN admin_users AS u ON u.id = s.user_id").
+		Joins("LEFT JOIN roles AS r ON r.name = u.role").
+		Where("s.id = ? AND u.id = ?", claims.SessionID, claims.UserID).
+		Take(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		} else {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return false
+	}
+	if row.RevokedAt != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has ended; log in again"})
+		return false
 	}
+	if row.Status != models.StatusActive {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Account is not active"})
+		return false
+	}
+
+	// Store user info in context
+	c.Set("user_id", claims.UserID)
+	c.Set("user_role", string(row.Role))
+	perms := rbac.NewSet(row.Permissions)
+	c.Set("user_permissions", perms)
+	c.Set("session_id", claims.SessionID)
+	c.Set("mfa_enrolment_required", !row.MFAEnabled && mfaEnrolmentRequired(perms))
+	return true
 }
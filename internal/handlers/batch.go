@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/calendar"
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxBatchDraws bounds how many draws one batch may run.
+const maxBatchDraws = 20
+
+type batchDrawItem struct {
+	PrizeStructureID string        `json:"prize_structure_id" binding:"required"`
+	MSISDNEntries    []MSISDNEntry `json:"msisdn_entries,omitempty"`
+}
+
+// batchDrawRequest lists draws to run in order on one date. Draws without their
+// own entries share the top-level upload or, failing that, the recharge ledger.
+type batchDrawRequest struct {
+	DrawDate      string          `json:"draw_date" binding:"required"`
+	MSISDNEntries []MSISDNEntry   `json:"msisdn_entries,omitempty"`
+	Draws         []batchDrawItem `json:"draws" binding:"required,min=1,dive"`
+}
+
+// ExecuteDrawBatch runs an ordered list of draws as one unit. Each draw's winners
+// are barred from the draws after it, so nobody wins twice in a batch. All draws
+// are computed before anything is written and saved in a single transaction;
+// if any draw fails, none is kept.
+func ExecuteDrawBatch(c *gin.Context) {
+	var req batchDrawRequest
+	if err := bindDrawRequest(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	if len(req.Draws) > maxBatchDraws {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many draws in one batch"})
+		return
+	}
+
+	drawDate, err := time.Parse("2006-01-02", req.DrawDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format; use YYYY-MM-DD"})
+		return
+	}
+
+	var existing models.Draw
+	if err := config.DB.Where("draw_date = ?", drawDate).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A draw was already executed for this date", "draw_id": existing.ID})
+		return
+	}
+
+	cal, err := calendar.Load(config.DB, drawDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()})
+		return
+	}
+	if blocked, ok := cal.NoDrawEntry(drawDate); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "No draw may run on this date", "calendar_type": blocked.Type, "calendar_name": blocked.Name})
+		return
+	}
+
+	structures := make([]models.PrizeStructure, len(req.Draws))
+	for i, item := range req.Draws {
+		psID, err := uuid.Parse(item.PrizeStructureID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Prize Structure ID format", "batch_index": i})
+			return
+		}
+		if err := config.DB.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index asc")
+		}).First(&structures[i], "id = ?", psID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Selected prize structure not found", "batch_index": i})
+			return
+		}
+		if len(req.MSISDNEntries) == 0 && len(item.MSISDNEntries) == 0 {
+			if failure := ledgerTierFilters(structures[i]); failure != nil {
+				failure["batch_index"] = i
+				c.JSON(http.StatusBadRequest, failure)
+				return
+			}
+		}
+	}
+
+	windowStart, windowEnd := cal.DrawWindow(drawDate)
+	var shared []models.EligibleEntry
+	var sharedEvents *gorm.DB
+	var window *ledgerWindow
+	sharedSource := "CSV"
+	if len(req.MSISDNEntries) > 0 {
+		shared = eligibleFromRows(req.MSISDNEntries)
+	} else {
+		for _, item := range req.Draws {
+			if len(item.MSISDNEntries) > 0 {
+				continue
+			}
+			sharedSource = drawSourceLedger
+			window = &ledgerWindow{Start: windowStart, End: windowEnd, Cutoff: time.Now()}
+			shared, err = window.pool()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()})
+				return
+			}
+			sharedEvents = window.events()
+			break
+		}
+	}
+
+	// The shared pool is screened once; per-draw uploads are screened as they come.
+	var sharedScreen *fraudScreen
+	if len(shared) > 0 {
+		var status int
+		var failure gin.H
+		if sharedScreen, status, failure = screenPool(shared, sharedEvents, windowStart, windowEnd); failure != nil {
+			c.JSON(status, failure)
+			return
+		}
+	}
+
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+	batchID := uuid.New()
+	batchWinners := make(map[string]bool)
+	runs := make([]*drawRun, len(req.Draws))
+	draws := make([]models.Draw, len(req.Draws))
+	for i, item := range req.Draws {
+		entries, source, pinned, screen := shared, sharedSource, window, sharedScreen
+		if len(item.MSISDNEntries) > 0 {
+			entries, source, pinned = eligibleFromRows(item.MSISDNEntries), "CSV", nil
+			var status int
+			var failure gin.H
+			if screen, status, failure = screenPool(entries, nil, windowStart, windowEnd); failure != nil {
+				failure["batch_index"] = i
+				c.JSON(status, failure)
+				return
+			}
+		}
+		if len(entries) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No eligible entries found for this draw", "batch_index": i})
+			return
+		}
+		run, status, failure := runDraw(structures[i], drawDate, entries, screen, batchWinners, "Draw failed")
+		if failure != nil {
+			failure["batch_index"] = i
+			c.JSON(status, failure)
+			return
+		}
+		for _, r := range run.Results {
+			batchWinners[r.MSISDN] = true
+		}
+		runs[i] = run
+		draws[i] = run.newDraw(drawDate, structures[i], adminUUID, source, false)
+		pinned.pin(&draws[i])
+		draws[i].BatchID = &batchID
+		draws[i].BatchSeq = i + 1
+	}
+
+	tx := config.DB.Begin()
+	results := make([]gin.H, len(runs))
+	for i, run := range runs {
+		winners, err := saveDraw(tx, &draws[i], structures[i], run)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save batch draw: " + err.Error(), "batch_index": i})
+			return
+		}
+		res := run.response(winners)
+		res["draw_id"] = draws[i].ID
+		res["prize_structure_id"] = structures[i].ID
+		res["batch_seq"] = draws[i].BatchSeq
+		results[i] = res
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit batch: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"batch_id": batchID, "draw_date": req.DrawDate, "draws": results})
+}
+
+// otherBatchWinners returns everyone drawn, runner-ups included, by the other
+// draws of d's batch, so a rerun of d keeps the batch rule that nobody wins
+// twice. A rerun takes its original's place, so only the latest draw at each
+// other position counts. It returns nil when d was not part of a batch.
+func otherBatchWinners(d models.Draw) (map[string]bool, error) {
+	if d.BatchID == nil {
+		return nil, nil
+	}
+	var siblings []models.Draw
+	if err := config.DB.Select("id, batch_seq").
+		Where("batch_id = ? AND batch_seq <> ?", *d.BatchID, d.BatchSeq).
+		Order("created_at desc").Find(&siblings).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(siblings))
+	var drawIDs []uuid.UUID
+	for _, s := range siblings {
+		if !seen[s.BatchSeq] {
+			seen[s.BatchSeq] = true
+			drawIDs = append(drawIDs, s.ID)
+		}
+	}
+	barred := make(map[string]bool)
+	if len(drawIDs) == 0 {
+		return barred, nil
+	}
+	var msisdns []string
+	if err := config.DB.Model(&models.Winner{}).Where("draw_id IN ?", drawIDs).Pluck("msisdn", &msisdns).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range msisdns {
+		barred[m] = true
+	}
+	return barred, nil
+}
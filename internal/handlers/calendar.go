@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/calendar"
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// calendarEntryRequest is the JSON payload for creating a calendar entry.
+type calendarEntryRequest struct {
+	Type             string `json:"type" binding:"required,oneof=HOLIDAY BLACKOUT EXTRA_DRAW"`
+	Name             string `json:"name" binding:"required"`
+	StartDate        string `json:"start_date" binding:"required"`
+	EndDate          string `json:"end_date,omitempty"`
+	PrizeStructureID string `json:"prize_structure_id,omitempty"`
+}
+
+// ListCalendarEntries handles GET /api/v1/calendar?from=yyyy-mm-dd&to=yyyy-mm-dd
+func ListCalendarEntries(c *gin.Context) {
+	q := config.DB.Order("start_date asc")
+	if from := c.Query("from"); from != "" {
+		fromDate, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date; use yyyy-mm-dd"})
+			return
+		}
+		q = q.Where("end_date >= ?", fromDate)
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date; use yyyy-mm-dd"})
+			return
+		}
+		q = q.Where("start_date <= ?", toDate)
+	}
+
+	var entries []models.CalendarEntry
+	if err := q.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar entries: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// CreateCalendarEntry handles POST /api/v1/calendar
+func CreateCalendarEntry(c *gin.Context) {
+	var req calendarEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date; use yyyy-mm-dd"})
+		return
+	}
+	endDate := startDate
+	if req.EndDate != "" {
+		endDate, err = time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date; use yyyy-mm-dd"})
+			return
+		}
+	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date cannot be before start date"})
+		return
+	}
+
+	entryType := models.CalendarEntryType(req.Type)
+	if entryType != models.CalendarBlackout && !endDate.Equal(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only blackout periods may span more than one day"})
+		return
+	}
+
+	var pinnedStructure *uuid.UUID
+	if req.PrizeStructureID != "" {
+		if entryType != models.CalendarExtraDraw {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only extra draw dates may pin a prize structure"})
+			return
+		}
+		pid, err := uuid.Parse(req.PrizeStructureID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prize structure ID"})
+			return
+		}
+		if err := config.DB.First(&models.PrizeStructure{}, "id = ?", pid).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Prize structure not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+			}
+			return
+		}
+		pinnedStructure = &pid
+	}
+
+	if entryType == models.CalendarExtraDraw {
+		cal, err := calendar.Load(config.DB, startDate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()})
+			return
+		}
+		if blocked, ok := cal.NoDrawEntry(startDate); ok {
+			c.JSON(http.StatusConflict, gin.H{"error": "Extra draw falls on a " + string(blocked.Type) + ": " + blocked.Name})
+			return
+		}
+	}
+
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+
+	entry := models.CalendarEntry{
+		ID:               uuid.New(),
+		Type:             entryType,
+		Name:             req.Name,
+		StartDate:        startDate,
+		EndDate:          endDate,
+		PrizeStructureID: pinnedStructure,
+		CreatedByID:      adminUUID,
+	}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar entry: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// DeleteCalendarEntry handles DELETE /api/v1/calendar/:id
+func DeleteCalendarEntry(c *gin.Context) {
+	eid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar entry ID"})
+		return
+	}
+	res := config.DB.Delete(&models.CalendarEntry{}, "id = ?", eid)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar entry: " + res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar entry not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
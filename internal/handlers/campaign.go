@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// campaignRequest is the JSON payload for creating/updating a campaign.
+type campaignRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	StartDate        string   `json:"start_date" binding:"required"`
+	EndDate          string   `json:"end_date,omitempty"`
+	CooldownDays     int      `json:"cooldown_days" binding:"gte=0"`
+	MaxWinsPerMSISDN int      `json:"max_wins_per_msisdn" binding:"gte=0"`
+	LockoutTiers     []string `json:"lockout_tiers,omitempty"`
+}
+
+func (req campaignRequest) apply(camp *models.Campaign) string {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return "Invalid start date; use yyyy-mm-dd"
+	}
+	var endDate *time.Time
+	if req.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return "Invalid end date; use yyyy-mm-dd"
+		}
+		if parsed.Before(startDate) {
+			return "End date cannot be before start date"
+		}
+		endDate = &parsed
+	}
+	camp.Name = req.Name
+	camp.StartDate = startDate
+	camp.EndDate = endDate
+	camp.CooldownDays = req.CooldownDays
+	camp.MaxWinsPerMSISDN = req.MaxWinsPerMSISDN
+	camp.LockoutTiers = req.LockoutTiers
+	return ""
+}
+
+// ListCampaigns handles GET /api/v1/campaigns
+func ListCampaigns(c *gin.Context) {
+	var campaigns []models.Campaign
+	if err := config.DB.Order("start_date desc").Find(&campaigns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaigns: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, campaigns)
+}
+
+// GetCampaign handles GET /api/v1/campaigns/:id
+func GetCampaign(c *gin.Context) {
+	cid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+	var camp models.Campaign
+	if err := config.DB.First(&camp, "id = ?", cid).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, camp)
+}
+
+// CreateCampaign handles POST /api/v1/campaigns
+func CreateCampaign(c *gin.Context) {
+	var req campaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	camp := models.Campaign{ID: uuid.New()}
+	if msg := req.apply(&camp); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if err := config.DB.Create(&camp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, camp)
+}
+
+// UpdateCampaign handles PUT /api/v1/campaigns/:id
+func UpdateCampaign(c *gin.Context) {
+	cid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+	var req campaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	var camp models.Campaign
+	if err := config.DB.First(&camp, "id = ?", cid).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		}
+		return
+	}
+	if msg := req.apply(&camp); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if err := config.DB.Save(&camp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, camp)
+}
@@ -1,15 +1,16 @@
 package handlers
 
 import (
-	"encoding/csv"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
+	"github.com/ArowuTest/promo-backend/internal/calendar"
 	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
 	"github.com/ArowuTest/promo-backend/internal/models"
-	"github.com/ArowuTest/promo-backend/internal/posthog"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
 	"github.com/ArowuTest/promo-backend/internal/rng"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,49 +18,27 @@ import (
 )
 
 type MSISDNEntry struct {
-	MSISDN string `json:"msisdn" binding:"required"`
-	Points int    `json:"points" binding:"required,gte=1"`
+	MSISDN      string `json:"msisdn" binding:"required"`
+	Points      int    `json:"points" binding:"required,gte=1"`
+	Segment     string `json:"segment,omitempty"`
+	Region      string `json:"region,omitempty"`
+	TenureDays  int    `json:"tenure_days,omitempty"`
+	ProductType string `json:"product_type,omitempty"`
 }
 
 type drawRequest struct {
 	DrawDate         string        `json:"draw_date" binding:"required"`
 	PrizeStructureID string        `json:"prize_structure_id" binding:"required"`
 	MSISDNEntries    []MSISDNEntry `json:"msisdn_entries,omitempty"`
-}
-
-func loadCsvEntries() ([]MSISDNEntry, error) {
-	f, err := os.Open("entries.csv")
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	reader := csv.NewReader(f)
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	var list []MSISDNEntry
-	for i, row := range rows {
-		if i == 0 {
-			continue
-		}
-		if len(row) < 2 {
-			continue
-		}
-		pts, err := strconv.Atoi(row[1])
-		if err != nil || pts < 1 {
-			continue
-		}
-		list = append(list, MSISDNEntry{MSISDN: row[0], Points: pts})
-	}
-	return list, nil
+	// IngestionJobID selects the pool of one loaded entry file or PostHog sync
+	// instead of the ledger window. PostHog totals are only drawn this way, and
+	// the sync must cover the draw's current entry window.
+	IngestionJobID   string        `json:"ingestion_job_id,omitempty"`
 }
 
 func ExecuteDraw(c *gin.Context) {
 	var req drawRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindDrawRequest(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()}); return
 	}
 
@@ -77,6 +56,14 @@ func ExecuteDraw(c *gin.Context) {
 		}); return
 	}
 
+	cal, err := calendar.Load(config.DB, drawDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()}); return
+	}
+	if blocked, ok := cal.NoDrawEntry(drawDate); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "No draw may run on this date", "calendar_type": blocked.Type, "calendar_name": blocked.Name}); return
+	}
+
 	prizeStructureUUID, err := uuid.Parse(req.PrizeStructureID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Prize Structure ID format"}); return
@@ -89,68 +76,70 @@ func ExecuteDraw(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Selected prize structure not found"}); return
 	}
 
+	if len(req.MSISDNEntries) == 0 {
+		if failure := ledgerTierFilters(prizeStruct); failure != nil {
+			c.JSON(http.StatusBadRequest, failure); return
+		}
+	}
+
+	windowStart, windowEnd := cal.DrawWindow(drawDate)
 	var entries []models.EligibleEntry
-	drawSource := "PostHog"
+	var events *gorm.DB
+	var window *ledgerWindow
+	var poolJobID *uuid.UUID
+	drawSource := drawSourceLedger
 	if len(req.MSISDNEntries) > 0 {
 		drawSource = "CSV"
-		for _, row := range req.MSISDNEntries {
-			entries = append(entries, models.EligibleEntry{MSISDN: row.MSISDN, Points: row.Points})
+		entries = eligibleFromRows(req.MSISDNEntries)
+	} else if req.IngestionJobID != "" {
+		job, msg := lookupPoolJob(req.IngestionJobID, drawDate)
+		if msg != "" { c.JSON(http.StatusBadRequest, gin.H{"error": msg}); return }
+		drawSource = drawSourceFile
+		if job.Source == ledger.SourcePostHog {
+			if job.Reference != ledger.SyncReference(windowStart, windowEnd) {
+				c.JSON(http.StatusConflict, gin.H{"error": "PostHog sync does not cover this draw's entry window; sync the draw date again", "sync_window": job.Reference}); return
+			}
+			drawSource = drawSourcePostHog
 		}
+		poolJobID = &job.ID
+		entries, err = ledger.JobPool(config.DB, job.ID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()}); return }
+		events = ledger.JobEvents(config.DB, job.ID)
 	} else {
-		windowStart, windowEnd := computePostHogWindow(drawDate)
-		phClient, _ := posthog.NewClient(config.Cfg)
-		defer phClient.Close()
-		phEntries, err := phClient.FetchEligibleEntries(windowStart, windowEnd)
-		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "PostHog fetch failed: " + err.Error()}); return }
-		entries = phEntries
+		window = &ledgerWindow{Start: windowStart, End: windowEnd, Cutoff: time.Now()}
+		entries, err = window.pool()
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()}); return }
+		events = window.events()
 	}
 
 	if len(entries) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No eligible entries found for this draw"}); return
 	}
 
-	var allPastWinners []models.Winner
-	config.DB.Find(&allPastWinners)
-	pastWinsByTier := make(map[string]map[uuid.UUID]bool)
-	for _, w := range allPastWinners {
-		if _, ok := pastWinsByTier[w.MSISDN]; !ok {
-			pastWinsByTier[w.MSISDN] = make(map[uuid.UUID]bool)
-		}
-		pastWinsByTier[w.MSISDN][w.PrizeTierID] = true
+	screen, status, failure := screenPool(entries, events, windowStart, windowEnd)
+	if failure != nil {
+		c.JSON(status, failure); return
 	}
 
-	drawResults, err := rng.DrawWinners(entries, prizeStruct.Tiers, pastWinsByTier)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Draw failed: " + err.Error()}); return
+	run, status, failure := runDraw(prizeStruct, drawDate, entries, screen, nil, "Draw failed")
+	if failure != nil {
+		c.JSON(status, failure); return
 	}
 
-	tx := config.DB.Begin()
-	newDrawID := uuid.New()
 	adminIDStr, _ := c.Get("user_id")
 	adminUUID, _ := uuid.Parse(adminIDStr.(string))
-	totalPoints := 0
-	for _, e := range entries { totalPoints += e.Points }
+	newDraw := run.newDraw(drawDate, prizeStruct, adminUUID, drawSource, false)
+	window.pin(&newDraw)
+	newDraw.IngestionJobID = poolJobID
 
-	newDraw := models.Draw{ID: newDrawID, DrawDate: drawDate, PrizeStructureID: prizeStruct.ID, TotalEntries: totalPoints, AdminUserID: adminUUID, Source: drawSource, IsRerun: false}
-	if err := tx.Create(&newDraw).Error; err != nil {
-		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save new draw"}); return
-	}
-
-	var responseWinners []gin.H
-	for _, winnerInfo := range drawResults {
-		var tierID uuid.UUID
-		for _, pt := range prizeStruct.Tiers {
-			if pt.TierName == winnerInfo.TierName { tierID = pt.ID; break }
-		}
-		newWinner := models.Winner{ID: uuid.New(), DrawID: newDrawID, PrizeTierID: tierID, MSISDN: winnerInfo.MSISDN, Position: winnerInfo.Position, IsRunnerUp: winnerInfo.IsRunnerUp}
-		if err := tx.Create(&newWinner).Error; err != nil {
-			tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save winner"}); return
-		}
-		responseWinners = append(responseWinners, gin.H{"prize_tier": winnerInfo.TierName, "position": winnerInfo.Position, "masked_msisdn": maskMSISDN(winnerInfo.MSISDN), "is_runner_up": winnerInfo.IsRunnerUp})
+	tx := config.DB.Begin()
+	responseWinners, err := saveDraw(tx, &newDraw, prizeStruct, run)
+	if err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save new draw: " + err.Error()}); return
 	}
 	tx.Commit()
 
-	c.JSON(http.StatusOK, gin.H{"winners": responseWinners})
+	c.JSON(http.StatusOK, run.response(responseWinners))
 }
 
 func RerunDraw(c *gin.Context) {
@@ -161,7 +150,7 @@ func RerunDraw(c *gin.Context) {
 	}
 
 	var req drawRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindDrawRequest(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload for rerun: " + err.Error()}); return
 	}
 	
@@ -179,94 +168,338 @@ func RerunDraw(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Prize structure for original draw not found"}); return
 	}
 
+	if len(req.MSISDNEntries) == 0 {
+		if failure := ledgerTierFilters(prizeStruct); failure != nil {
+			c.JSON(http.StatusBadRequest, failure); return
+		}
+	}
+
+	window, err := rerunWindow(oldDraw)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()}); return }
+	windowStart, windowEnd := window.Start, window.End
+
 	var entries []models.EligibleEntry
-	drawSource := "PostHog"
+	var events *gorm.DB
+	drawSource := drawSourceLedger
 	if len(req.MSISDNEntries) > 0 {
 		drawSource = "CSV"
-		for _, row := range req.MSISDNEntries {
-			entries = append(entries, models.EligibleEntry{MSISDN: row.MSISDN, Points: row.Points})
-		}
+		entries = eligibleFromRows(req.MSISDNEntries)
+		window = nil
+	} else if oldDraw.IngestionJobID != nil {
+		drawSource = oldDraw.Source
+		entries, err = ledger.JobPool(config.DB, *oldDraw.IngestionJobID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger for rerun: " + err.Error()}); return }
+		events = ledger.JobEvents(config.DB, *oldDraw.IngestionJobID)
+		window = nil
 	} else {
-		windowStart, windowEnd := computePostHogWindow(drawDate)
-		phClient, _ := posthog.NewClient(config.Cfg)
-		defer phClient.Close()
-		phEntries, err := phClient.FetchEligibleEntries(windowStart, windowEnd)
-		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "PostHog fetch failed for rerun: " + err.Error()}); return }
-		entries = phEntries
+		entries, err = window.pool()
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger for rerun: " + err.Error()}); return }
+		events = window.events()
 	}
 
 	if len(entries) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No eligible entries found for this draw's window"}); return
 	}
 
-	var allPastWinners []models.Winner
-	config.DB.Find(&allPastWinners)
-	pastWinsByTier := make(map[string]map[uuid.UUID]bool)
-	for _, w := range allPastWinners {
-		if _, ok := pastWinsByTier[w.MSISDN]; !ok {
-			pastWinsByTier[w.MSISDN] = make(map[uuid.UUID]bool)
-		}
-		pastWinsByTier[w.MSISDN][w.PrizeTierID] = true
+	screen, status, failure := screenPool(entries, events, windowStart, windowEnd)
+	if failure != nil {
+		c.JSON(status, failure); return
 	}
 
-	rerunRes, err := rng.DrawWinners(entries, prizeStruct.Tiers, pastWinsByTier)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Rerun draw failed: " + err.Error()}); return
+	barred, err := otherBatchWinners(oldDraw)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load batch winners: " + err.Error()}); return }
+
+	run, status, failure := runDraw(prizeStruct, drawDate, entries, screen, barred, "Rerun draw failed")
+	if failure != nil {
+		c.JSON(status, failure); return
 	}
 
-	tx := config.DB.Begin()
-	newDrawID := uuid.New()
 	adminID, _ := c.Get("user_id")
 	adminUUID, _ := uuid.Parse(adminID.(string))
+	// The original draw is NOT updated. This was the bug.
+	// We simply create a new draw with IsRerun=true.
+	newDraw := run.newDraw(drawDate, prizeStruct, adminUUID, drawSource, true)
+	window.pin(&newDraw)
+	if drawSource == drawSourceFile || drawSource == drawSourcePostHog {
+		newDraw.IngestionJobID = oldDraw.IngestionJobID
+	}
+	// The rerun takes the original's place in its batch.
+	newDraw.BatchID, newDraw.BatchSeq = oldDraw.BatchID, oldDraw.BatchSeq
+
+	tx := config.DB.Begin()
+	responseWinners, err := saveDraw(tx, &newDraw, prizeStruct, run)
+	if err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rerun draw: " + err.Error()}); return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, run.response(responseWinners))
+}
+
+// drawRun is one draw computed in memory and not yet saved.
+type drawRun struct {
+	Entries    []models.EligibleEntry
+	Exclusions []models.DrawExclusionStat
+	Weighting  rng.Weighting
+	Results    []rng.WinnerResult
+	TierPools  []rng.TierPool
+	Trace      *rng.SelectionTrace
+	Warning    gin.H
+	FraudFlags []models.DrawFraudFlag
+}
+
+// maxDrawRequestBody bounds draw requests. Inline msisdn_entries are for small
+// pools; large entry files are streamed in through POST /ledger/uploads and
+// drawn with ingestion_job_id.
+const maxDrawRequestBody = 16 << 20
+
+// bindDrawRequest binds a draw request body of at most maxDrawRequestBody bytes.
+func bindDrawRequest(c *gin.Context, req any) error {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDrawRequestBody)
+	err := c.ShouldBindJSON(req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("body exceeds %d MiB; upload large entry files to /ledger/uploads and pass ingestion_job_id", maxDrawRequestBody>>20)
+	}
+	return err
+}
+
+// eligibleFromRows converts uploaded rows to pool entries.
+func eligibleFromRows(rows []MSISDNEntry) []models.EligibleEntry {
+	entries := make([]models.EligibleEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, models.EligibleEntry{MSISDN: row.MSISDN, Points: row.Points, Segment: row.Segment, Region: row.Region, TenureDays: row.TenureDays, ProductType: row.ProductType})
+	}
+	return entries
+}
+
+// ledgerTierFilters refuses a structure whose tiers filter on segment, region,
+// product type or tenure when the pool comes from the recharge ledger, which
+// only records each MSISDN's points; every entry would fail such a tier. It
+// returns nil when the structure can be drawn from the ledger.
+func ledgerTierFilters(ps models.PrizeStructure) gin.H {
+	for _, t := range ps.Tiers {
+		if len(t.Segments) > 0 || len(t.Regions) > 0 || len(t.ProductTypes) > 0 || t.MinTenureDays > 0 {
+			return gin.H{"error": "Tier " + t.TierName + " filters on subscriber attributes the recharge ledger does not record; upload msisdn_entries with those columns instead", "tier": t.TierName}
+		}
+	}
+	return nil
+}
+
+// runDraw drops entries on an exclusion list or barred by the campaign's winner
+// rules, then draws prizeStruct's tiers from what is left. screen's flags are
+// recorded and, in EXCLUDE mode, drop their entries; it may be nil. MSISDNs in
+// barred are also dropped, as BATCH_WINNER; it may be nil. On failure it
+// returns the status and body to respond with.
+func runDraw(prizeStruct models.PrizeStructure, drawDate time.Time, entries []models.EligibleEntry, screen *fraudScreen, barred map[string]bool, failPrefix string) (*drawRun, int, gin.H) {
+	entries, exclusionStats, err := applyExclusions(entries, drawDate)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": "Failed to load exclusion lists: " + err.Error()}
+	}
+	entries, fraudStats, fraudFlags := screen.apply(entries)
+	exclusionStats = append(exclusionStats, fraudStats...)
+	if len(entries) == 0 {
+		return nil, http.StatusBadRequest, gin.H{"error": "Every eligible entry is on an exclusion list or flagged as fraud", "exclusions": exclusionStats}
+	}
+
+	weighting := rng.Weighting{Strategy: rng.WeightStrategy(prizeStruct.WeightStrategy), Cap: prizeStruct.WeightCap}
+	if err := weighting.Validate(); err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": "Prize structure has an invalid weighting: " + err.Error()}
+	}
+
+	history, err := loadWinnerHistory(prizeStruct, drawDate)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": "Failed to load winner history: " + err.Error()}
+	}
+	for number := range barred {
+		history.bar([]string{number}, ruleBatchWinner)
+	}
+	entries, ruleStats := applyWinnerRules(entries, history)
+	exclusionStats = append(exclusionStats, ruleStats...)
+	if len(entries) == 0 {
+		return nil, http.StatusBadRequest, gin.H{"error": "Every eligible entry is barred by exclusion lists or winner rules", "exclusions": exclusionStats}
+	}
+
+	var trace *rng.SelectionTrace
+	if config.Cfg.DrawTrace { trace = &rng.SelectionTrace{} }
+	results, tierPools, err := rng.DrawWinners(entries, prizeStruct.Tiers, history.PastWinsByTier, weighting, trace)
+	warning, err := drawWarning(err)
+	if err != nil {
+		status, body := drawErrorResponse(failPrefix, err)
+		if errors.Is(err, rng.ErrPoolExhausted) { body["tier_pools"] = tierPools; body["exclusions"] = exclusionStats }
+		return nil, status, body
+	}
+	return &drawRun{Entries: entries, Exclusions: exclusionStats, Weighting: weighting, Results: results, TierPools: tierPools, Trace: trace, Warning: warning, FraudFlags: fraudFlags}, 0, nil
+}
+
+// newDraw returns the Draw row describing run.
+func (run *drawRun) newDraw(drawDate time.Time, prizeStruct models.PrizeStructure, adminID uuid.UUID, source string, isRerun bool) models.Draw {
 	totalPoints := 0
-	for _, e := range entries { totalPoints += e.Points }
+	for _, e := range run.Entries { totalPoints += e.Points }
+	return models.Draw{ID: uuid.New(), DrawDate: drawDate, PrizeStructureID: prizeStruct.ID, TotalEntries: totalPoints, AdminUserID: adminID, Source: source, IsRerun: isRerun,
+		ExcludedEntries: exclusionTotal(run.Exclusions), WeightStrategy: string(run.Weighting.Strategy), WeightCap: run.Weighting.Cap}
+}
 
-	newDraw := models.Draw{ID: newDrawID, DrawDate: drawDate, PrizeStructureID: prizeStruct.ID, TotalEntries: totalPoints, AdminUserID: adminUUID, Source: drawSource, IsRerun: true}
-	if err := tx.Create(&newDraw).Error; err != nil {
-		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rerun draw"}); return
+// saveDraw writes draw with its exclusion report, tier pools, selection trace
+// and winners inside tx, and returns the winners as shown in responses. The
+// caller commits or rolls back tx.
+func saveDraw(tx *gorm.DB, draw *models.Draw, prizeStruct models.PrizeStructure, run *drawRun) ([]gin.H, error) {
+	if err := tx.Create(draw).Error; err != nil {
+		return nil, err
+	}
+	for i := range run.Exclusions {
+		run.Exclusions[i].DrawID = draw.ID
+		if err := tx.Create(&run.Exclusions[i]).Error; err != nil {
+			return nil, fmt.Errorf("exclusion report: %w", err)
+		}
+	}
+	for _, p := range run.TierPools {
+		pool := models.DrawTierPool{ID: uuid.New(), DrawID: draw.ID, TierName: p.TierName, PoolEntries: p.Entries, PoolPoints: p.Points, ExcludedPastWinners: p.ExcludedPastWinners,
+			WinnersRequested: p.WinnersRequested, WinnersDrawn: p.WinnersDrawn, RunnerUpsRequested: p.RunnerUpsRequested, RunnerUpsDrawn: p.RunnerUpsDrawn}
+		if err := tx.Create(&pool).Error; err != nil {
+			return nil, fmt.Errorf("tier pool report: %w", err)
+		}
+	}
+	if err := saveSelectionTrace(tx, draw.ID, run.Trace); err != nil {
+		return nil, fmt.Errorf("selection trace: %w", err)
+	}
+	// Winner and flag MSISDNs are stored in msisdn.Key form, so campaign
+	// rules and fraud reviews can match them in SQL.
+	held := make(map[string]bool)
+	for i := range run.FraudFlags {
+		run.FraudFlags[i].DrawID = draw.ID
+		run.FraudFlags[i].MSISDN = msisdn.Key(run.FraudFlags[i].MSISDN)
+		if run.FraudFlags[i].Action == fraudActionHeld {
+			held[run.FraudFlags[i].MSISDN] = true
+		}
+	}
+	if len(run.FraudFlags) > 0 {
+		if err := tx.CreateInBatches(run.FraudFlags, 500).Error; err != nil {
+			return nil, fmt.Errorf("fraud flags: %w", err)
+		}
 	}
-	
-	// The original draw is NOT updated. This was the bug.
-	// We simply create a new draw with IsRerun=true.
 
 	var responseWinners []gin.H
-	for _, winnerInfo := range rerunRes {
+	for _, winnerInfo := range run.Results {
 		var tierID uuid.UUID
 		for _, pt := range prizeStruct.Tiers {
 			if pt.TierName == winnerInfo.TierName { tierID = pt.ID; break }
 		}
-		newWinner := models.Winner{ID: uuid.New(), DrawID: newDrawID, PrizeTierID: tierID, MSISDN: winnerInfo.MSISDN, Position: winnerInfo.Position, IsRunnerUp: winnerInfo.IsRunnerUp}
+		newWinner := models.Winner{ID: uuid.New(), DrawID: draw.ID, PrizeTierID: tierID, MSISDN: msisdn.Key(winnerInfo.MSISDN), Position: winnerInfo.Position, IsRunnerUp: winnerInfo.IsRunnerUp, Status: models.WinnerAwarded}
+		if held[newWinner.MSISDN] { newWinner.Status = models.WinnerHeld }
 		if err := tx.Create(&newWinner).Error; err != nil {
-			tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rerun winner"}); return
+			return nil, fmt.Errorf("winner: %w", err)
 		}
-		responseWinners = append(responseWinners, gin.H{"prize_tier": winnerInfo.TierName, "position": winnerInfo.Position, "masked_msisdn": maskMSISDN(winnerInfo.MSISDN), "is_runner_up": winnerInfo.IsRunnerUp})
+		responseWinners = append(responseWinners, gin.H{"prize_tier": winnerInfo.TierName, "position": winnerInfo.Position, "masked_msisdn": maskMSISDN(winnerInfo.MSISDN), "is_runner_up": winnerInfo.IsRunnerUp, "status": newWinner.Status, "held_for_review": newWinner.Status == models.WinnerHeld})
 	}
-	tx.Commit()
+	return responseWinners, nil
+}
 
-	c.JSON(http.StatusOK, gin.H{"winners": responseWinners})
+// response is the body returned for a saved draw.
+func (run *drawRun) response(winners []gin.H) gin.H {
+	return gin.H{"winners": winners, "excluded_entries": exclusionTotal(run.Exclusions), "exclusions": run.Exclusions, "tier_pools": run.TierPools, "unfilled_tiers": unfilledTiers(run.TierPools), "weight_strategy": run.Weighting.Strategy, "warning": run.Warning, "fraud_flags": fraudSummary(run.FraudFlags)}
 }
 
 func ListDraws(c *gin.Context) {
 	var draws []models.Draw
-	if err := config.DB.Preload("AdminUser").Order("draw_date desc").Find(&draws).Error; err != nil {
+	if err := config.DB.Preload("AdminUser").Preload("Exclusions").Preload("TierPools").Order("draw_date desc").Find(&draws).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch draws: " + err.Error()}); return
 	}
 	c.JSON(http.StatusOK, draws)
 }
 
-func computePostHogWindow(drawDate time.Time) (time.Time, time.Time) {
-	weekday := drawDate.Weekday()
-	windowEnd := time.Date(drawDate.Year(), drawDate.Month(), drawDate.Day(), 17, 0, 0, 0, drawDate.Location())
-	var windowStart time.Time
-	switch weekday {
-	case time.Monday:
-		windowStart = windowEnd.AddDate(0, 0, -3).Add(time.Second)
-	case time.Saturday:
-		windowStart = windowEnd.AddDate(0, 0, -7).Add(time.Second)
-	default:
-		windowStart = windowEnd.AddDate(0, 0, -1).Add(time.Second)
-	}
-	return windowStart, windowEnd
+// Draw sources for pools read from the recharge ledger: a draw window, the
+// rows of one file, or the totals of one PostHog sync.
+const (
+	drawSourceLedger  = "LEDGER"
+	drawSourceFile    = "FILE"
+	drawSourcePostHog = "POSTHOG"
+)
+
+// lookupPoolJob returns the completed file or PostHog sync job named by raw.
+// A job made for a particular draw date can only be used on that date; a zero
+// drawDate skips that check.
+func lookupPoolJob(raw string, drawDate time.Time) (*models.IngestionJob, string) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, "Invalid ingestion job ID"
+	}
+	var job models.IngestionJob
+	if err := config.DB.First(&job, "id = ? AND source IN ?", id, []string{ledger.SourceFile, ledger.SourceUpload, ledger.SourcePostHog}).Error; err != nil {
+		return nil, "Ingestion job not found"
+	}
+	if job.Status != models.IngestionCompleted {
+		return nil, "Ingestion job did not complete successfully"
+	}
+	if job.DrawDate != nil && !drawDate.IsZero() && !job.DrawDate.Equal(drawDate) {
+		return nil, "Ingestion job was made for the draw on " + job.DrawDate.Format("2006-01-02")
+	}
+	return &job, ""
+}
+
+// ledgerWindow is the slice of the recharge ledger a draw selects from: events
+// that occurred in [Start, End] and were ingested no later than Cutoff.
+type ledgerWindow struct {
+	Start, End, Cutoff time.Time
+}
+
+func (w *ledgerWindow) pool() ([]models.EligibleEntry, error) {
+	return ledger.Pool(config.DB, w.Start, w.End, w.Cutoff)
+}
+
+// events selects the ledger events behind the window's pool.
+func (w *ledgerWindow) events() *gorm.DB {
+	return ledger.WindowEvents(config.DB, w.Start, w.End, w.Cutoff)
+}
+
+// pin records the window on d so reruns can read the same rows. It is a no-op on a nil window.
+func (w *ledgerWindow) pin(d *models.Draw) {
+	if w == nil {
+		return
+	}
+	start, end, cutoff := w.Start, w.End, w.Cutoff
+	d.WindowStart, d.WindowEnd, d.LedgerCutoff = &start, &end, &cutoff
+}
+
+// rerunWindow returns the ledger window pinned on draw. Draws made before the
+// ledger existed have none, so their window is recomputed and read as of now.
+func rerunWindow(draw models.Draw) (*ledgerWindow, error) {
+	if draw.WindowStart != nil && draw.WindowEnd != nil && draw.LedgerCutoff != nil {
+		return &ledgerWindow{Start: *draw.WindowStart, End: *draw.WindowEnd, Cutoff: *draw.LedgerCutoff}, nil
+	}
+	windowStart, windowEnd, err := computeDrawWindow(draw.DrawDate)
+	if err != nil {
+		return nil, err
+	}
+	return &ledgerWindow{Start: windowStart, End: windowEnd, Cutoff: time.Now()}, nil
+}
+
+// computeDrawWindow returns the entry window for drawDate, taking holidays,
+// blackouts and extra draw dates from the draw calendar into account.
+func computeDrawWindow(drawDate time.Time) (time.Time, time.Time, error) {
+	cal, err := calendar.Load(config.DB, drawDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	windowStart, windowEnd := cal.DrawWindow(drawDate)
+	return windowStart, windowEnd, nil
+}
+
+// unfilledTiers lists the tiers whose pool ran out before every prize was awarded.
+func unfilledTiers(pools []rng.TierPool) []gin.H {
+	var short []gin.H
+	for _, p := range pools {
+		if p.Unfilled() {
+			short = append(short, gin.H{
+				"prize_tier":           p.TierName,
+				"winners_requested":    p.WinnersRequested,
+				"winners_drawn":        p.WinnersDrawn,
+				"runner_ups_requested": p.RunnerUpsRequested,
+				"runner_ups_drawn":     p.RunnerUpsDrawn,
+			})
+		}
+	}
+	return short
 }
 
 func maskMSISDN(msisdn string) string {
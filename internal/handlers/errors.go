@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ArowuTest/promo-backend/internal/rng"
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable codes returned alongside draw errors and warnings.
+const (
+	codeInvalidTierConfig = "INVALID_TIER_CONFIG"
+	codePoolExhausted     = "POOL_EXHAUSTED"
+	codeTierUnfillable    = "TIER_UNFILLABLE"
+	codeRNGHealthFailure  = "RNG_HEALTH_FAILURE"
+	codeRNGFailure        = "RNG_FAILURE"
+	codeDrawFailed        = "DRAW_FAILED"
+)
+
+// drawErrorResponse maps an error from rng.DrawWinners to an HTTP status and
+// response body. A *rng.PartialFillError is not fatal and is handled by the
+// caller with drawWarning instead.
+func drawErrorResponse(prefix string, err error) (int, gin.H) {
+	status, code := http.StatusInternalServerError, codeDrawFailed
+	switch {
+	case errors.Is(err, rng.ErrInvalidTierConfig):
+		status, code = http.StatusUnprocessableEntity, codeInvalidTierConfig
+	case errors.Is(err, rng.ErrPoolExhausted):
+		status, code = http.StatusConflict, codePoolExhausted
+	case errors.Is(err, rng.ErrHealthTestFailed):
+		status, code = http.StatusServiceUnavailable, codeRNGHealthFailure
+		prefix = "Draws are blocked"
+	case errors.Is(err, rng.ErrRNGFailure):
+		status, code = http.StatusServiceUnavailable, codeRNGFailure
+	}
+	body := gin.H{"error": prefix + ": " + err.Error(), "code": code}
+	var tierErr *rng.TierConfigError
+	if errors.As(err, &tierErr) {
+		body["prize_tier"] = tierErr.Tier
+	}
+	return status, body
+}
+
+// drawWarning splits a DrawWinners error into a fatal error and a warning body.
+// Partial fills still produce winners, so they are reported rather than failed.
+func drawWarning(err error) (gin.H, error) {
+	var partial *rng.PartialFillError
+	if errors.As(err, &partial) {
+		return gin.H{"code": codeTierUnfillable, "message": partial.Error()}, nil
+	}
+	return nil, err
+}
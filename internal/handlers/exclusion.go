@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// exclusionRequest is the JSON payload for adding a single exclusion.
+type exclusionRequest struct {
+	MSISDN    string `json:"msisdn" binding:"required"`
+	Category  string `json:"category" binding:"required,oneof=STAFF RELATIVE FRAUD OTHER"`
+	Reason    string `json:"reason" binding:"required"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// exclusionImportRequest is the JSON payload for bulk-importing MSISDNs under one reason.
+type exclusionImportRequest struct {
+	MSISDNs   []string `json:"msisdns" binding:"required,min=1"`
+	Category  string   `json:"category" binding:"required,oneof=STAFF RELATIVE FRAUD OTHER"`
+	Reason    string   `json:"reason" binding:"required"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+}
+
+func parseExpiry(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// activeExclusions loads every exclusion in force on date, keyed by msisdn.Key,
+// so local and international spellings of a number match.
+func activeExclusions(date time.Time) (map[string]models.ExclusionEntry, error) {
+	var list []models.ExclusionEntry
+	if err := config.DB.
+		Where("expires_at IS NULL OR expires_at >= ?", date).
+		Order("created_at asc").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	byMSISDN := make(map[string]models.ExclusionEntry, len(list))
+	for _, e := range list {
+		if _, seen := byMSISDN[msisdn.Key(e.MSISDN)]; !seen {
+			byMSISDN[msisdn.Key(e.MSISDN)] = e
+		}
+	}
+	return byMSISDN, nil
+}
+
+// applyExclusions drops entries barred by an exclusion active on drawDate and
+// tallies how many were dropped per category.
+func applyExclusions(entries []models.EligibleEntry, drawDate time.Time) ([]models.EligibleEntry, []models.DrawExclusionStat, error) {
+	excluded, err := activeExclusions(drawDate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(excluded) == 0 {
+		return entries, nil, nil
+	}
+
+	counts := make(map[string]int)
+	var kept []models.EligibleEntry
+	for _, e := range entries {
+		if ex, ok := excluded[msisdn.Key(e.MSISDN)]; ok {
+			counts[string(ex.Category)]++
+			continue
+		}
+		kept = append(kept, e)
+	}
+
+	var stats []models.DrawExclusionStat
+	for category, n := range counts {
+		stats = append(stats, models.DrawExclusionStat{ID: uuid.New(), Category: category, Count: n})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Category < stats[j].Category })
+	return kept, stats, nil
+}
+
+func exclusionTotal(stats []models.DrawExclusionStat) int {
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+	}
+	return total
+}
+
+// ListExclusions handles GET /api/v1/exclusions?msisdn=&active=true
+func ListExclusions(c *gin.Context) {
+	q := config.DB.Order("created_at desc")
+	if c.Query("active") == "true" {
+		q = q.Where("expires_at IS NULL OR expires_at >= ?", time.Now())
+	}
+
+	var list []models.ExclusionEntry
+	if err := q.Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exclusions: " + err.Error()})
+		return
+	}
+
+	// Match numbers the way draws do, so any spelling of a number finds it,
+	// including rows stored before numbers were normalized.
+	want := ""
+	if raw := c.Query("msisdn"); raw != "" {
+		want = msisdn.Key(raw)
+	}
+	var resp []gin.H
+	for _, e := range list {
+		if want != "" && msisdn.Key(e.MSISDN) != want {
+			continue
+		}
+		resp = append(resp, gin.H{
+			"id":          e.ID,
+			"msisdn":      e.MSISDN,
+			"category":    e.Category,
+			"reason":      e.Reason,
+			"expires_at":  e.ExpiresAt,
+			"added_by_id": e.AddedByID,
+			"created":     e.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateExclusion handles POST /api/v1/exclusions
+func CreateExclusion(c *gin.Context) {
+	var req exclusionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	expiresAt, err := parseExpiry(req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expires_at; use yyyy-mm-dd"})
+		return
+	}
+
+	number, err := msisdn.Normalize(req.MSISDN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid MSISDN: " + strings.TrimSpace(req.MSISDN)})
+		return
+	}
+	active, err := activeExclusions(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exclusions: " + err.Error()})
+		return
+	}
+	if existing, ok := active[number]; ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "MSISDN is already excluded", "id": existing.ID, "category": existing.Category})
+		return
+	}
+
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+
+	entry := models.ExclusionEntry{
+		ID:        uuid.New(),
+		MSISDN:    number,
+		Category:  models.ExclusionCategory(req.Category),
+		Reason:    req.Reason,
+		ExpiresAt: expiresAt,
+		AddedByID: adminUUID,
+	}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exclusion: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         entry.ID,
+		"msisdn":     entry.MSISDN,
+		"category":   entry.Category,
+		"reason":     entry.Reason,
+		"expires_at": entry.ExpiresAt,
+	})
+}
+
+// ImportExclusions handles POST /api/v1/exclusions/import
+func ImportExclusions(c *gin.Context) {
+	var req exclusionImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	expiresAt, err := parseExpiry(req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expires_at; use yyyy-mm-dd"})
+		return
+	}
+
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+
+	// Numbers already under an active exclusion are skipped, so re-importing a
+	// list does not pile up duplicates.
+	active, err := activeExclusions(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exclusions: " + err.Error()})
+		return
+	}
+	seen := make(map[string]bool)
+	var rows []models.ExclusionEntry
+	var invalid []string
+	skipped, valid := 0, 0
+	for _, raw := range req.MSISDNs {
+		number, err := msisdn.Normalize(raw)
+		if err != nil {
+			if strings.TrimSpace(raw) != "" {
+				invalid = append(invalid, strings.TrimSpace(raw))
+			}
+			skipped++
+			continue
+		}
+		valid++
+		if _, excluded := active[number]; excluded || seen[number] {
+			skipped++
+			continue
+		}
+		seen[number] = true
+		rows = append(rows, models.ExclusionEntry{
+			ID:        uuid.New(),
+			MSISDN:    number,
+			Category:  models.ExclusionCategory(req.Category),
+			Reason:    req.Reason,
+			ExpiresAt: expiresAt,
+			AddedByID: adminUUID,
+		})
+	}
+	if valid == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid MSISDNs supplied", "invalid": invalid})
+		return
+	}
+
+	if len(rows) > 0 {
+		if err := config.DB.CreateInBatches(&rows, 500).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import exclusions: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusCreated, gin.H{"imported": len(rows), "skipped": skipped, "invalid": invalid})
+}
+
+// DeleteExclusion handles DELETE /api/v1/exclusions/:id
+func DeleteExclusion(c *gin.Context) {
+	eid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exclusion ID"})
+		return
+	}
+	res := config.DB.Delete(&models.ExclusionEntry{}, "id = ?", eid)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exclusion: " + res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exclusion not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
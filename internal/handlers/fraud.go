@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/fraud"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ruleFraudFlag is the exclusion category counted for entries dropped by the fraud screen.
+const ruleFraudFlag = "FRAUD_FLAG"
+
+// Actions recorded on a draw's fraud flags.
+const (
+	fraudActionExcluded = "EXCLUDED"
+	fraudActionHeld     = "HELD"
+)
+
+// errFlagSettled reports a review of a flag that is not pending.
+var errFlagSettled = errors.New("fraud flag already reviewed")
+
+// fraudSettingsRequest is the JSON payload replacing the fraud settings.
+type fraudSettingsRequest struct {
+	Enabled             bool   `json:"enabled"`
+	Mode                string `json:"mode" binding:"required,oneof=EXCLUDE HOLD"`
+	SequentialBlockMin  int    `json:"sequential_block_min"`
+	PatternMinNumbers   int    `json:"pattern_min_numbers"`
+	PatternMinEvents    int    `json:"pattern_min_events"`
+	MaxRechargesPerDay  int    `json:"max_recharges_per_day"`
+	HistoryFactor       int    `json:"history_factor"`
+	HistoryMinPoints    int    `json:"history_min_points"`
+	HistoryLookbackDays int    `json:"history_lookback_days"`
+}
+
+// fraudReviewRequest settles a held flag. Confirming it also bars the MSISDN
+// from future draws with a FRAUD exclusion and voids what it won in the draw.
+type fraudReviewRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=CLEAR CONFIRM"`
+	Note     string `json:"note"`
+}
+
+// fraudScreen is the fraud screen's verdict on one pool.
+type fraudScreen struct {
+	Mode  string
+	Flags []fraud.Flag
+}
+
+// screenPool runs the fraud screen with the stored settings. events selects the
+// ledger events behind entries, or is nil for pools uploaded inline.
+func screenPool(entries []models.EligibleEntry, events *gorm.DB, windowStart, windowEnd time.Time) (*fraudScreen, int, gin.H) {
+	settings, err := fraud.Load(config.DB)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": "Failed to load fraud settings: " + err.Error()}
+	}
+	flags, err := fraud.Screen(config.DB, settings, fraud.Pool{Entries: entries, Events: events, WindowStart: windowStart, WindowEnd: windowEnd})
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": "Fraud screening failed: " + err.Error()}
+	}
+	return &fraudScreen{Mode: settings.Mode, Flags: flags}, 0, nil
+}
+
+// apply records the flags raised on entries and, in EXCLUDE mode, drops the
+// flagged entries, tallying them under FRAUD_FLAG. A nil screen changes nothing.
+func (s *fraudScreen) apply(entries []models.EligibleEntry) ([]models.EligibleEntry, []models.DrawExclusionStat, []models.DrawFraudFlag) {
+	if s == nil || len(s.Flags) == 0 {
+		return entries, nil, nil
+	}
+	inPool := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		inPool[e.MSISDN] = true
+	}
+	action, review := fraudActionHeld, models.FraudReviewPending
+	if s.Mode == models.FraudModeExclude {
+		action, review = fraudActionExcluded, ""
+	}
+	flagged := make(map[string]bool)
+	var records []models.DrawFraudFlag
+	for _, f := range s.Flags {
+		if !inPool[f.MSISDN] {
+			continue // already dropped by an exclusion list
+		}
+		flagged[f.MSISDN] = true
+		records = append(records, models.DrawFraudFlag{ID: uuid.New(), MSISDN: f.MSISDN, Rule: f.Rule, Detail: f.Detail, Points: f.Points, Action: action, ReviewStatus: review})
+	}
+	if action != fraudActionExcluded || len(flagged) == 0 {
+		return entries, nil, records
+	}
+	kept := make([]models.EligibleEntry, 0, len(entries)-len(flagged))
+	for _, e := range entries {
+		if !flagged[e.MSISDN] {
+			kept = append(kept, e)
+		}
+	}
+	return kept, []models.DrawExclusionStat{{ID: uuid.New(), Category: ruleFraudFlag, Count: len(flagged)}}, records
+}
+
+// fraudSummary counts a draw's fraud flags per rule for responses.
+func fraudSummary(flags []models.DrawFraudFlag) gin.H {
+	byRule := make(map[string]int)
+	numbers := make(map[string]bool)
+	for _, f := range flags {
+		byRule[f.Rule]++
+		numbers[f.MSISDN] = true
+	}
+	return gin.H{"flagged_msisdns": len(numbers), "by_rule": byRule}
+}
+
+// GetFraudSettings handles GET /api/v1/fraud/settings
+func GetFraudSettings(c *gin.Context) {
+	settings, err := fraud.Load(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load fraud settings: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateFraudSettings handles PUT /api/v1/fraud/settings. Thresholds set to 0
+// switch their check off.
+func UpdateFraudSettings(c *gin.Context) {
+	var req fraudSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	current, err := fraud.Load(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load fraud settings: " + err.Error()})
+		return
+	}
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+
+	settings := models.FraudSettings{
+		ID:                  current.ID,
+		Enabled:             req.Enabled,
+		Mode:                req.Mode,
+		SequentialBlockMin:  req.SequentialBlockMin,
+		PatternMinNumbers:   req.PatternMinNumbers,
+		PatternMinEvents:    req.PatternMinEvents,
+		MaxRechargesPerDay:  req.MaxRechargesPerDay,
+		HistoryFactor:       req.HistoryFactor,
+		HistoryMinPoints:    req.HistoryMinPoints,
+		HistoryLookbackDays: req.HistoryLookbackDays,
+		UpdatedByID:         &adminUUID,
+	}
+	if err := fraud.Validate(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if settings.ID == uuid.Nil {
+		settings.ID = uuid.New()
+	}
+	// Save writes every column, so a disabled screen or a zero threshold sticks.
+	if err := config.DB.Save(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save fraud settings: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// ListDrawFraudFlags handles GET /api/v1/draws/:id/fraud-flags?status=PENDING
+func ListDrawFraudFlags(c *gin.Context) {
+	drawID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draw ID"})
+		return
+	}
+	q := config.DB.Where("draw_id = ?", drawID).Order("msisdn asc, rule asc")
+	if status := c.Query("status"); status != "" {
+		q = q.Where("review_status = ?", strings.ToUpper(status))
+	}
+	var flags []models.DrawFraudFlag
+	if err := q.Find(&flags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fraud flags: " + err.Error()})
+		return
+	}
+
+	// Show which flagged numbers won, since those are the reviews that matter most.
+	var winners []models.Winner
+	if err := config.DB.Where("draw_id = ?", drawID).Find(&winners).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch winners: " + err.Error()})
+		return
+	}
+	won := make(map[string]bool, len(winners))
+	for _, w := range winners {
+		won[w.MSISDN] = true
+	}
+	resp := make([]gin.H, 0, len(flags))
+	for _, f := range flags {
+		resp = append(resp, gin.H{
+			"id":             f.ID,
+			"msisdn":         f.MSISDN,
+			"rule":           f.Rule,
+			"detail":         f.Detail,
+			"points":         f.Points,
+			"action":         f.Action,
+			"review_status":  f.ReviewStatus,
+			"review_note":    f.ReviewNote,
+			"reviewed_by_id": f.ReviewedByID,
+			"reviewed_at":    f.ReviewedAt,
+			"is_winner":      won[f.MSISDN],
+		})
+	}
+	sort.SliceStable(resp, func(i, j int) bool { return resp[i]["is_winner"].(bool) && !resp[j]["is_winner"].(bool) })
+	c.JSON(http.StatusOK, resp)
+}
+
+// settleHeldWinners applies flag's review to the held prizes its MSISDN won in
+// the flag's draw. Clearing the last pending flag awards them; confirming voids
+// them and gives each voided winner's place to the first runner-up left in its
+// tier. It returns the runner-ups promoted.
+func settleHeldWinners(tx *gorm.DB, flag models.DrawFraudFlag) ([]models.Winner, error) {
+	var held []models.Winner
+	if err := tx.Where("draw_id = ? AND msisdn = ? AND status = ?", flag.DrawID, flag.MSISDN, models.WinnerHeld).Find(&held).Error; err != nil {
+		return nil, err
+	}
+	if len(held) == 0 {
+		return nil, nil
+	}
+	if flag.ReviewStatus == models.FraudReviewCleared {
+		var pending int64
+		if err := tx.Model(&models.DrawFraudFlag{}).
+			Where("draw_id = ? AND msisdn = ? AND review_status = ?", flag.DrawID, flag.MSISDN, models.FraudReviewPending).
+			Count(&pending).Error; err != nil {
+			return nil, err
+		}
+		if pending > 0 {
+			return nil, nil
+		}
+		return nil, tx.Model(&models.Winner{}).Where("draw_id = ? AND msisdn = ? AND status = ?", flag.DrawID, flag.MSISDN, models.WinnerHeld).
+			Update("status", models.WinnerAwarded).Error
+	}
+
+	var promoted []models.Winner
+	for _, w := range held {
+		if err := tx.Model(&models.Winner{}).Where("id = ?", w.ID).Update("status", models.WinnerVoided).Error; err != nil {
+			return nil, err
+		}
+		if w.IsRunnerUp {
+			continue
+		}
+		var next models.Winner
+		err := tx.Where("draw_id = ? AND prize_tier_id = ? AND is_runner_up = ? AND status <> ?", w.DrawID, w.PrizeTierID, true, models.WinnerVoided).
+			Order("position asc").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue // the prize stays unawarded
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&models.Winner{}).Where("id = ?", next.ID).
+			Updates(map[string]interface{}{"is_runner_up": false, "position": w.Position}).Error; err != nil {
+			return nil, err
+		}
+		next.IsRunnerUp, next.Position = false, w.Position
+		promoted = append(promoted, next)
+	}
+	return promoted, nil
+}
+
+// ReviewFraudFlag handles POST /api/v1/fraud/flags/:id/review
+func ReviewFraudFlag(c *gin.Context) {
+	flagID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fraud flag ID"})
+		return
+	}
+	var req fraudReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+
+	var flag models.DrawFraudFlag
+	var promoted []models.Winner
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&flag, "id = ?", flagID).Error; err != nil {
+			return err
+		}
+		if flag.ReviewStatus != models.FraudReviewPending {
+			return errFlagSettled
+		}
+		now := time.Now()
+		flag.ReviewStatus = models.FraudReviewCleared
+		if req.Decision == "CONFIRM" {
+			flag.ReviewStatus = models.FraudReviewConfirmed
+			exclusion := models.ExclusionEntry{
+				ID:        uuid.New(),
+				MSISDN:    flag.MSISDN,
+				Category:  models.ExclusionFraud,
+				Reason:    "Confirmed fraud flag " + flag.Rule + ": " + flag.Detail,
+				AddedByID: adminUUID,
+			}
+			if err := tx.Create(&exclusion).Error; err != nil {
+				return err
+			}
+		}
+		flag.ReviewNote = req.Note
+		flag.ReviewedByID = &adminUUID
+		flag.ReviewedAt = &now
+		if err := tx.Save(&flag).Error; err != nil {
+			return err
+		}
+		var err error
+		promoted, err = settleHeldWinners(tx, flag)
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fraud flag not found"})
+	case errors.Is(err, errFlagSettled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Fraud flag is not awaiting review", "review_status": flag.ReviewStatus})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review fraud flag: " + err.Error()})
+	default:
+		resp := make([]gin.H, 0, len(promoted))
+		for _, w := range promoted {
+			resp = append(resp, gin.H{"id": w.ID, "masked_msisdn": maskMSISDN(w.MSISDN), "position": w.Position, "status": w.Status})
+		}
+		c.JSON(http.StatusOK, gin.H{"flag": flag, "promoted_runner_ups": resp})
+	}
+}
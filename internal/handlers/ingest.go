@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
+	"github.com/ArowuTest/promo-backend/internal/points"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// ingestSignatureHeader carries "sha256=<hex>", the HMAC-SHA256 of
+	// "<timestamp>.<raw body>" under INGEST_HMAC_SECRET.
+	ingestSignatureHeader = "X-Ingest-Signature"
+	// ingestTimestampHeader carries the Unix time the batch was signed.
+	ingestTimestampHeader = "X-Ingest-Timestamp"
+	// ingestMaxSkew bounds how old or early a signed batch may be, to limit replays.
+	ingestMaxSkew = 5 * time.Minute
+	// ingestMaxBody and ingestMaxEvents bound a single pushed batch.
+	ingestMaxBody   = 10 << 20
+	ingestMaxEvents = 5000
+)
+
+// rechargeEventRequest is one pushed recharge. Amount is in naira, with at most two decimals.
+type rechargeEventRequest struct {
+	ExternalID string      `json:"external_id"`
+	MSISDN     string      `json:"msisdn"`
+	Amount     json.Number `json:"amount"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Channel    string      `json:"channel"`
+}
+
+type rechargeBatchRequest struct {
+	Events []rechargeEventRequest `json:"events" binding:"required,min=1"`
+}
+
+// RequireIngestSignature verifies the HMAC signature on a pushed batch before
+// the handler sees it. The raw body is restored for binding afterwards.
+func RequireIngestSignature() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := config.Cfg.IngestHMACSecret
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Recharge ingestion is not configured"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, ingestMaxBody))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large or unreadable"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ts := c.GetHeader(ingestTimestampHeader)
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid " + ingestTimestampHeader + " header"})
+			return
+		}
+		if skew := time.Since(time.Unix(unix, 0)); skew > ingestMaxSkew || skew < -ingestMaxSkew {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Signature timestamp outside the allowed window"})
+			return
+		}
+
+		sig, err := hex.DecodeString(strings.TrimPrefix(c.GetHeader(ingestSignatureHeader), "sha256="))
+		if err != nil || len(sig) == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid " + ingestSignatureHeader + " header"})
+			return
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Signature mismatch"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// toRechargeEvent validates one pushed event and converts it to a ledger row.
+func (r rechargeEventRequest) toRechargeEvent() (models.RechargeEvent, error) {
+	externalID := strings.TrimSpace(r.ExternalID)
+	if externalID == "" {
+		return models.RechargeEvent{}, errors.New("external_id is required")
+	}
+	number, err := msisdn.Normalize(r.MSISDN)
+	if err != nil {
+		return models.RechargeEvent{}, err
+	}
+	kobo, err := points.ParseNaira(r.Amount.String())
+	if err != nil {
+		return models.RechargeEvent{}, err
+	}
+	if r.Timestamp.IsZero() {
+		return models.RechargeEvent{}, errors.New("timestamp is required")
+	}
+	return models.RechargeEvent{
+		ExternalID: externalID,
+		MSISDN:     number,
+		AmountKobo: kobo,
+		Channel:    strings.ToUpper(strings.TrimSpace(r.Channel)),
+		OccurredAt: r.Timestamp,
+	}, nil
+}
+
+// scoreNewEvents drops events already in the ledger, counting them as
+// duplicates, and freezes points onto the rest. db must hold ledger.LockCaps
+// until the events are recorded.
+func scoreNewEvents(db *gorm.DB, job *models.IngestionJob, events *[]models.RechargeEvent) error {
+	ids := make([]string, 0, len(*events))
+	for _, ev := range *events {
+		ids = append(ids, ev.ExternalID)
+	}
+	existing, err := ledger.Existing(db, ids)
+	if err != nil {
+		return err
+	}
+	fresh := (*events)[:0]
+	for _, ev := range *events {
+		if existing[ev.ExternalID] {
+			job.Duplicates++
+			continue
+		}
+		fresh = append(fresh, ev)
+	}
+	*events = fresh
+
+	scorer, err := ledger.NewScorer(db)
+	if err != nil {
+		return err
+	}
+	return scorer.ScoreInOrder(fresh)
+}
+
+// IngestRecharges handles POST /api/v1/ingest/recharges
+//
+// Valid events are scored by the points rules in force and stored in the
+// recharge ledger; events whose external_id is already stored are counted as
+// duplicates, and invalid events are reported by index without failing the
+// rest of the batch.
+func IngestRecharges(c *gin.Context) {
+	var req rechargeBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	if len(req.Events) > ingestMaxEvents {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Too many events in one batch", "max_events": ingestMaxEvents})
+		return
+	}
+
+	job, err := ledger.StartJob(config.DB, ledger.SourceAPI, c.GetHeader("X-Request-ID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start ingestion job: " + err.Error()})
+		return
+	}
+	job.Received = len(req.Events)
+
+	var rejected []gin.H
+	seen := make(map[string]bool, len(req.Events))
+	events := make([]models.RechargeEvent, 0, len(req.Events))
+	for i, r := range req.Events {
+		ev, err := r.toRechargeEvent()
+		if err != nil {
+			rejected = append(rejected, gin.H{"index": i, "external_id": r.ExternalID, "error": err.Error()})
+			continue
+		}
+		if seen[ev.ExternalID] {
+			job.Duplicates++
+			continue
+		}
+		seen[ev.ExternalID] = true
+		events = append(events, ev)
+	}
+	job.Rejected = len(rejected)
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ledger.LockCaps(tx); err != nil {
+			return err
+		}
+		if err := scoreNewEvents(tx, job, &events); err != nil {
+			return err
+		}
+		_, err := ledger.Record(tx, job, events)
+		return err
+	})
+	if ferr := ledger.FinishJob(config.DB, job, err); ferr != nil && err == nil {
+		err = ferr
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store recharge events: " + err.Error(), "job_id": job.ID})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":     job.ID,
+		"received":   job.Received,
+		"inserted":   job.Inserted,
+		"duplicates": job.Duplicates,
+		"rejected":   rejected,
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const testIngestSecret = "test-ingest-secret"
+
+func signIngest(ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testIngestSecret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// serveIngest sends body through RequireIngestSignature to a handler that
+// echoes the body it was given.
+func serveIngest(t *testing.T, body []byte, ts, sig string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	config.Cfg = &config.AppConfig{IngestHMACSecret: testIngestSecret}
+
+	r := gin.New()
+	r.POST("/ingest", RequireIngestSignature(), func(c *gin.Context) {
+		got, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			t.Fatalf("reading restored body: %v", err)
+		}
+		c.Data(http.StatusOK, "application/octet-stream", got)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(body))
+	req.Header.Set(ingestTimestampHeader, ts)
+	req.Header.Set(ingestSignatureHeader, sig)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireIngestSignature(t *testing.T) {
+	body := []byte(`{"events":[{"external_id":"r1","msisdn":"08030000001","amount":"100","timestamp":"2025-06-02T10:00:00Z"}]}`)
+	now := time.Now().Unix()
+	stamp := func(offset time.Duration) string { return strconv.FormatInt(now+int64(offset/time.Second), 10) }
+
+	tests := []struct {
+		name string
+		body []byte
+		ts   string
+		sig  string
+		want int
+	}{
+		{"valid signature", body, stamp(0), signIngest(stamp(0), body), http.StatusOK},
+		{"tampered body", bytes.Replace(body, []byte(`"100"`), []byte(`"900"`), 1), stamp(0), signIngest(stamp(0), body), http.StatusUnauthorized},
+		{"signed under another timestamp", body, stamp(0), signIngest(stamp(-time.Second), body), http.StatusUnauthorized},
+		{"just inside the past skew", body, stamp(-ingestMaxSkew + 5*time.Second), signIngest(stamp(-ingestMaxSkew+5*time.Second), body), http.StatusOK},
+		{"just inside the future skew", body, stamp(ingestMaxSkew - 5*time.Second), signIngest(stamp(ingestMaxSkew-5*time.Second), body), http.StatusOK},
+		{"just outside the past skew", body, stamp(-ingestMaxSkew - 5*time.Second), signIngest(stamp(-ingestMaxSkew-5*time.Second), body), http.StatusUnauthorized},
+		{"just outside the future skew", body, stamp(ingestMaxSkew + 5*time.Second), signIngest(stamp(ingestMaxSkew+5*time.Second), body), http.StatusUnauthorized},
+		{"missing timestamp", body, "", signIngest("", body), http.StatusUnauthorized},
+		{"missing signature", body, stamp(0), "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveIngest(t, tt.body, tt.ts, tt.sig)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK && !bytes.Equal(w.Body.Bytes(), tt.body) {
+				t.Errorf("handler saw body %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestRequireIngestSignatureBodyCap(t *testing.T) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	atCap := bytes.Repeat([]byte{' '}, ingestMaxBody)
+	if w := serveIngest(t, atCap, ts, signIngest(ts, atCap)); w.Code != http.StatusOK {
+		t.Errorf("body of exactly %d bytes: status = %d, want %d", ingestMaxBody, w.Code, http.StatusOK)
+	}
+
+	overCap := bytes.Repeat([]byte{' '}, ingestMaxBody+1)
+	if w := serveIngest(t, overCap, ts, signIngest(ts, overCap)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body of %d bytes: status = %d, want %d", ingestMaxBody+1, w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestRequireIngestSignatureUnconfigured(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Cfg = &config.AppConfig{}
+	r := gin.New()
+	r.POST("/ingest", RequireIngestSignature(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(nil)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
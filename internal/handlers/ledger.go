@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/posthog"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ledgerSyncRequest names the draw date whose entry window is copied from PostHog.
+type ledgerSyncRequest struct {
+	DrawDate string `json:"draw_date" binding:"required"`
+}
+
+// ListIngestionJobs handles GET /api/v1/ledger/jobs?source=FILE&status=RUNNING&draw_date=yyyy-mm-dd
+func ListIngestionJobs(c *gin.Context) {
+	q := config.DB.Order("started_at desc").Limit(200)
+	if source := c.Query("source"); source != "" {
+		q = q.Where("source = ?", source)
+	}
+	if status := c.Query("status"); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if d := c.Query("draw_date"); d != "" {
+		drawDate, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draw_date; use yyyy-mm-dd"})
+			return
+		}
+		q = q.Where("draw_date = ?", drawDate)
+	}
+	var jobs []models.IngestionJob
+	if err := q.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ingestion jobs: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// GetIngestionJob handles GET /api/v1/ledger/jobs/:id. Polling a running
+// upload's job shows its progress in bytes_read of bytes_total.
+func GetIngestionJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ingestion job ID"})
+		return
+	}
+	var job models.IngestionJob
+	if err := config.DB.First(&job, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ingestion job not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// uploadBody returns the entry file in an upload request and its name: the
+// "file" part of a multipart form, or else the raw body named by ?name=.
+// Neither is held in memory, so files of any size can be uploaded.
+func uploadBody(c *gin.Context) (io.Reader, string, error) {
+	mr, err := c.Request.MultipartReader()
+	if errors.Is(err, http.ErrNotMultipart) {
+		return c.Request.Body, c.Query("name"), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New(`multipart upload has no "file" part`)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			return part, filepath.Base(part.FileName()), nil
+		}
+	}
+}
+
+// UploadEntryFile handles POST /api/v1/ledger/uploads?draw_date=yyyy-mm-dd
+//
+// The body is an entry CSV, optionally gzipped, sent raw or as the "file" part
+// of a multipart form. Once received it is answered with 202 and an UPLOAD
+// ingestion job, and streamed into the ledger in the background; like
+// drop-folder files it loads completely or not at all. Poll
+// GET /ledger/jobs/:id for progress and the outcome, including the row errors
+// of a refused file; a completed job's pool can then be drawn with
+// ingestion_job_id. draw_date defaults to a date in the file name.
+func UploadEntryFile(c *gin.Context) {
+	body, name, err := uploadBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload: " + err.Error()})
+		return
+	}
+
+	drawDate := ledger.DrawDateFromName(name)
+	if d := c.Query("draw_date"); d != "" {
+		parsed, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draw_date; use yyyy-mm-dd"})
+			return
+		}
+		drawDate = &parsed
+	}
+	// Rows without a timestamp belong to the end of the draw date's entry window.
+	var defaultTime time.Time
+	if drawDate != nil {
+		if _, defaultTime, err = computeDrawWindow(*drawDate); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()})
+			return
+		}
+	}
+
+	// The body only lives as long as the request, so it is spooled to disk
+	// and loaded from there after the response.
+	spool, err := os.CreateTemp("", "entry-upload-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload: " + err.Error()})
+		return
+	}
+	size, err := io.Copy(spool, body)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		discardSpool(spool)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to receive upload: " + err.Error()})
+		return
+	}
+
+	job, err := ledger.StartJob(config.DB, ledger.SourceUpload, name)
+	if err != nil {
+		discardSpool(spool)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start ingestion job: " + err.Error()})
+		return
+	}
+	job.DrawDate = drawDate
+	job.BytesTotal = size
+	if err := config.DB.Save(job).Error; err != nil {
+		discardSpool(spool)
+		ledger.FinishJob(config.DB, job, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start ingestion job: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+	go loadUpload(job, spool, defaultTime)
+}
+
+// loadUpload streams a spooled upload into the ledger under job and removes
+// the spool. Nobody waits on the outcome, so a refused file's row errors are
+// kept in the job's error.
+func loadUpload(job *models.IngestionJob, spool *os.File, defaultTime time.Time) {
+	defer discardSpool(spool)
+	rowErrors, loadErr := ledger.LoadCSV(context.Background(), config.DB, job, spool, "upload:"+job.ID.String(), defaultTime)
+	if loadErr != nil && len(rowErrors) > 0 {
+		loadErr = fmt.Errorf("%w\n%s", loadErr, strings.Join(rowErrors, "\n"))
+	}
+	if err := ledger.FinishJob(config.DB, job, loadErr); err != nil {
+		log.Printf("upload %s: saving job: %v", job.ID, err)
+	}
+}
+
+func discardSpool(spool *os.File) {
+	spool.Close()
+	os.Remove(spool.Name())
+}
+
+// GetLedgerPool handles GET /api/v1/ledger/pool?draw_date=yyyy-mm-dd and
+// returns the pool a draw on that date would select from right now.
+func GetLedgerPool(c *gin.Context) {
+	drawDate, err := time.Parse("2006-01-02", c.Query("draw_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draw_date; use yyyy-mm-dd"})
+		return
+	}
+	windowStart, windowEnd, err := computeDrawWindow(drawDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()})
+		return
+	}
+	cutoff := time.Now()
+	entries, err := ledger.Pool(config.DB, windowStart, windowEnd, cutoff)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()})
+		return
+	}
+	totalPoints := 0
+	for _, e := range entries {
+		totalPoints += e.Points
+	}
+	c.JSON(http.StatusOK, gin.H{"window_start": windowStart, "window_end": windowEnd, "cutoff": cutoff, "entries": len(entries), "total_points": totalPoints})
+}
+
+// SyncPostHogLedger handles POST /api/v1/ledger/sync/posthog. It copies the
+// PostHog totals for the draw date's entry window into the ledger. Ledger
+// window draws do not read them: a draw selects them by passing the returned
+// job's ID as ingestion_job_id.
+func SyncPostHogLedger(c *gin.Context) {
+	var req ledgerSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	drawDate, err := time.Parse("2006-01-02", req.DrawDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format; use yyyy-mm-dd"})
+		return
+	}
+	windowStart, windowEnd, err := computeDrawWindow(drawDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()})
+		return
+	}
+
+	phClient, _ := posthog.NewClient(config.Cfg)
+	defer phClient.Close()
+	job, err := ledger.SyncPostHog(config.DB, phClient, drawDate, windowStart, windowEnd)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "PostHog sync failed: " + err.Error(), "job": job})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// userMaxFailures and ipMaxFailures are the failed logins that lock a
+	// user or a client IP. An IP gets more room since offices share one.
+	userMaxFailures = 5
+	ipMaxFailures   = 20
+	// lockoutBase is the first lockout; each one in a row doubles it up to lockoutMax.
+	lockoutBase = time.Minute
+	lockoutMax  = 24 * time.Hour
+	// failureDecay forgets a key's failures and lockouts after this long without one.
+	failureDecay = 24 * time.Hour
+)
+
+func userThrottleKey(id uuid.UUID) string { return "user:" + id.String() }
+func ipThrottleKey(ip string) string      { return "ip:" + ip }
+
+// lockedFor returns how long key stays locked, or zero.
+func lockedFor(db *gorm.DB, key string) (time.Duration, error) {
+	var t models.LoginThrottle
+	err := db.First(&t, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil || t.LockedUntil == nil {
+		return 0, err
+	}
+	if wait := time.Until(*t.LockedUntil); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// recordFailure counts a failed login against key and locks it once the
+// failures reach limit.
+func recordFailure(db *gorm.DB, key string, limit int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginThrottle{Key: key}).Error; err != nil {
+			return err
+		}
+		var t models.LoginThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, "key = ?", key).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if now.Sub(t.LastFailureAt) > failureDecay {
+			t.Failures, t.Lockouts = 0, 0
+		}
+		t.Failures++
+		t.LastFailureAt = now
+		if t.Failures >= limit {
+			wait := lockoutBase << t.Lockouts
+			if wait > lockoutMax || wait <= 0 {
+				wait = lockoutMax
+			}
+			until := now.Add(wait)
+			t.LockedUntil = &until
+			t.Failures = 0
+			t.Lockouts++
+		}
+		return tx.Save(&t).Error
+	})
+}
+
+// recordLoginFailure counts a failed login against the client IP and, when
+// known, the user.
+func recordLoginFailure(c *gin.Context, userID *uuid.UUID) error {
+	if err := recordFailure(config.DB, ipThrottleKey(c.ClientIP()), ipMaxFailures); err != nil {
+		return err
+	}
+	if userID == nil {
+		return nil
+	}
+	return recordFailure(config.DB, userThrottleKey(*userID), userMaxFailures)
+}
+
+// clearUserFailures forgets a user's failed logins after a successful one.
+// The IP's count is kept, so one good account cannot launder guesses at others.
+func clearUserFailures(db *gorm.DB, userID uuid.UUID) error {
+	return db.Delete(&models.LoginThrottle{}, "key = ?", userThrottleKey(userID)).Error
+}
+
+// refuseLocked answers a login refused by a lockout.
+func refuseLocked(c *gin.Context, wait time.Duration) {
+	secs := int(wait.Round(time.Second).Seconds())
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed logins; try again later", "retry_after": secs})
+}
+
+// UnlockUser handles POST /api/v1/admin/users/:id/unlock. It lifts a
+// temporary lockout and, for a user whose status is Locked, sets it Active.
+func UnlockUser(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var user models.AdminUser
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", uid).Error; err != nil {
+			return err
+		}
+		if ok, err := outranks(c, user.Role); err != nil {
+			return err
+		} else if !ok {
+			return errOutranked
+		}
+		if err := clearUserFailures(tx, uid); err != nil {
+			return err
+		}
+		if user.Status != models.StatusLocked {
+			return nil
+		}
+		user.Status = models.StatusActive
+		return tx.Model(&user).Updates(map[string]interface{}{"status": user.Status, "updated_at": time.Now()}).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, errOutranked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot unlock a user whose role has permissions you do not hold"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"status":   user.Status,
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/auth"
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// mfaChallengeTTL is how long the second login step stays open.
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaChallengeAttempts is how many wrong codes end a challenge.
+	mfaChallengeAttempts = 5
+	// recoveryCodeCount is how many recovery codes an enrolment issues.
+	recoveryCodeCount = 10
+)
+
+// errMFAChallengeInvalid rejects an unknown, expired, spent or exhausted challenge.
+var errMFAChallengeInvalid = errors.New("mfa challenge invalid")
+
+// mfaLoginRequest is the JSON payload for /admin/login/mfa. Code is a TOTP
+// code or a recovery code.
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// mfaCodeRequest carries a TOTP or recovery code.
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// mfaDisableRequest turns MFA off; both factors are asked for again.
+type mfaDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// mfaEnrolmentRequired reports whether a holder of perms must enrol before
+// using the API.
+func mfaEnrolmentRequired(perms rbac.Set) bool {
+	return config.Cfg.MFAEnforce && perms.NeedsMFA()
+}
+
+// isTOTPCode tells a six-digit TOTP code from a recovery code.
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hashRecoveryCode returns the stored form of a recovery code, ignoring case,
+// spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return auth.HashOpaqueToken(code)
+}
+
+// issueRecoveryCodes replaces userID's recovery codes and returns the new ones.
+func issueRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]models.MFARecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		rows[i] = models.MFARecoveryCode{ID: uuid.New(), UserID: userID, CodeHash: hashRecoveryCode(raw)}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkMFACode verifies code for user, who must be locked in tx. A TOTP code
+// advances the user's last accepted step; a recovery code is spent.
+func checkMFACode(tx *gorm.DB, user *models.AdminUser, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		secret, err := auth.Open(user.MFASecret)
+		if err != nil {
+			return false, err
+		}
+		step, ok := auth.VerifyTOTP(secret, code, time.Now(), user.MFALastStep)
+		if !ok {
+			return false, nil
+		}
+		user.MFALastStep = step
+		return true, tx.Model(user).Update("mfa_last_step", step).Error
+	}
+	res := tx.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// lockUser loads the authenticated user FOR UPDATE.
+func lockUser(tx *gorm.DB, c *gin.Context) (models.AdminUser, error) {
+	var user models.AdminUser
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", c.GetString("user_id")).Error
+	return user, err
+}
+
+// startMFAChallenge opens the second login step for user.
+func startMFAChallenge(user models.AdminUser) (gin.H, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	challenge := models.MFAChallenge{ID: uuid.New(), UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(mfaChallengeTTL)}
+	if err := config.DB.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"mfa_required": true,
+		"mfa_token":    token,
+		"expires_in":   int(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// LoginMFA handles POST /api/v1/admin/login/mfa, the second step of a login
+// for users with MFA. A wrong code counts against the challenge, which ends
+// after mfaChallengeAttempts of them, and towards the login lockout.
+func LoginMFA(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	var user models.AdminUser
+	var accepted bool
+	var wait time.Duration
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var challenge models.MFAChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&challenge, "token_hash = ?", auth.HashOpaqueToken(req.MFAToken)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errMFAChallengeInvalid
+			}
+			return err
+		}
+		if challenge.UsedAt != nil || challenge.Attempts >= mfaChallengeAttempts || time.Now().After(challenge.ExpiresAt) {
+			return errMFAChallengeInvalid
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", challenge.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errMFAChallengeInvalid
+			}
+			return err
+		}
+		if user.Status != models.StatusActive || !user.MFAEnabled {
+			return errMFAChallengeInvalid
+		}
+		var err error
+		if wait, err = lockedFor(tx, userThrottleKey(user.ID)); err != nil || wait > 0 {
+			return err
+		}
+
+		accepted, err = checkMFACode(tx, &user, req.Code)
+		if err != nil {
+			return err
+		}
+		// A failed attempt is committed too, so guesses stay counted.
+		if !accepted {
+			return tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+		if err := tx.Model(&challenge).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return clearUserFailures(tx, user.ID)
+	})
+	switch {
+	case errors.Is(err, errMFAChallengeInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge expired; log in again"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code: " + err.Error()})
+		return
+	case wait > 0:
+		refuseLocked(c, wait)
+		return
+	case !accepted:
+		if err := recordLoginFailure(c, &user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	body, err := startSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// GetMFAStatus handles GET /api/v1/auth/mfa
+func GetMFAStatus(c *gin.Context) {
+	var user models.AdminUser
+	if err := config.DB.First(&user, "id = ?", c.GetString("user_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+	var remaining int64
+	if err := config.DB.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.MFAEnabled,
+		"enrolled_at":              user.MFAEnrolledAt,
+		"required":                 permissions(c).NeedsMFA(),
+		"enforced":                 mfaEnrolmentRequired(permissions(c)),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollMFA handles POST /api/v1/auth/mfa/enroll. It returns a new secret and
+// its provisioning URI for the authenticator's QR code; MFA is switched on
+// only once ConfirmMFA accepts a code from it.
+func EnrollMFA(c *gin.Context) {
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	sealed, err := auth.Seal(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store secret: " + err.Error()})
+		return
+	}
+
+	var user models.AdminUser
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = lockUser(tx, c); err != nil {
+			return err
+		}
+		if user.MFAEnabled {
+			return nil
+		}
+		return tx.Model(&user).Update("mfa_pending_secret", sealed).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrolment: " + err.Error()})
+		return
+	}
+	if user.MFAEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled; disable it before enrolling again"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPURI(config.Cfg.MFAIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmMFA handles POST /api/v1/auth/mfa/confirm. A code from the pending
+// secret enables MFA and returns the recovery codes, which are shown only once.
+func ConfirmMFA(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	var status int
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, c)
+		if err != nil {
+			return err
+		}
+		if user.MFAEnabled || user.MFAPendingSecret == "" {
+			status = http.StatusConflict
+			return nil
+		}
+		secret, err := auth.Open(user.MFAPendingSecret)
+		if err != nil {
+			return err
+		}
+		step, ok := auth.VerifyTOTP(secret, req.Code, time.Now(), 0)
+		if !ok {
+			status = http.StatusBadRequest
+			return nil
+		}
+		now := time.Now()
+		err = tx.Model(&user).Updates(map[string]interface{}{
+			"mfa_enabled":        true,
+			"mfa_secret":         user.MFAPendingSecret,
+			"mfa_pending_secret": "",
+			"mfa_last_step":      step,
+			"mfa_enrolled_at":    now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		codes, err = issueRecoveryCodes(tx, user.ID)
+		return err
+	})
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm enrolment: " + err.Error()})
+	case status == http.StatusConflict:
+		c.JSON(status, gin.H{"error": "No enrolment is pending"})
+	case status == http.StatusBadRequest:
+		c.JSON(status, gin.H{"error": "Invalid authentication code"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+	}
+}
+
+// RegenerateRecoveryCodes handles POST /api/v1/auth/mfa/recovery-codes and
+// replaces every recovery code of the caller.
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	var status int
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, c)
+		if err != nil {
+			return err
+		}
+		if !user.MFAEnabled {
+			status = http.StatusConflict
+			return nil
+		}
+		ok, err := checkMFACode(tx, &user, req.Code)
+		if err != nil || !ok {
+			status = http.StatusBadRequest
+			return err
+		}
+		codes, err = issueRecoveryCodes(tx, user.ID)
+		return err
+	})
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue recovery codes: " + err.Error()})
+	case status == http.StatusConflict:
+		c.JSON(status, gin.H{"error": "MFA is not enabled"})
+	case status == http.StatusBadRequest:
+		c.JSON(status, gin.H{"error": "Invalid authentication code"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// DisableMFA handles DELETE /api/v1/auth/mfa. Users whose role grants a
+// sensitive permission cannot turn it off while MFA is enforced.
+func DisableMFA(c *gin.Context) {
+	var req mfaDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	if mfaEnrolmentRequired(permissions(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "MFA is required for your role"})
+		return
+	}
+
+	var status int
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, c)
+		if err != nil {
+			return err
+		}
+		if !user.MFAEnabled {
+			status = http.StatusConflict
+			return nil
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+			status = http.StatusUnauthorized
+			return nil
+		}
+		ok, err := checkMFACode(tx, &user, req.Code)
+		if err != nil || !ok {
+			status = http.StatusUnauthorized
+			return err
+		}
+		return clearMFA(tx, user.ID)
+	})
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable MFA: " + err.Error()})
+	case status == http.StatusConflict:
+		c.JSON(status, gin.H{"error": "MFA is not enabled"})
+	case status == http.StatusUnauthorized:
+		c.JSON(status, gin.H{"error": "Invalid password or authentication code"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// clearMFA removes userID's enrolment and recovery codes.
+func clearMFA(tx *gorm.DB, userID uuid.UUID) error {
+	err := tx.Model(&models.AdminUser{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"mfa_enabled":        false,
+		"mfa_secret":         "",
+		"mfa_pending_secret": "",
+		"mfa_last_step":      0,
+		"mfa_enrolled_at":    nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error
+}
+
+// ResetUserMFA handles POST /api/v1/admin/users/:id/mfa/reset for a user who
+// lost both their authenticator and recovery codes. Their sessions end and
+// they enrol again at next login.
+func ResetUserMFA(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.AdminUser
+		if err := tx.First(&user, "id = ?", uid).Error; err != nil {
+			return err
+		}
+		if ok, err := outranks(c, user.Role); err != nil {
+			return err
+		} else if !ok {
+			return errOutranked
+		}
+		if err := clearMFA(tx, uid); err != nil {
+			return err
+		}
+		return revokeSessions(tx, uid, revokedMFAReset)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, errOutranked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot reset MFA for a user whose role has permissions you do not hold"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset MFA: " + err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
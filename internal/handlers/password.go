@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/auth"
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/mail"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// passwordResetTTL is how long a mailed reset link works.
+const passwordResetTTL = time.Hour
+
+// Mailer sends password reset links. main sets it once at startup; tests can
+// replace it with a fake.
+var Mailer mail.Sender
+
+// errResetTokenInvalid rejects an unknown, expired or spent reset token.
+var errResetTokenInvalid = errors.New("reset token invalid")
+
+// changePasswordRequest is the JSON payload for /auth/password.
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// resetPasswordRequest is the JSON payload for /auth/password/reset.
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// setPassword checks pw against the policy for user and stores its hash on user.
+func setPassword(user *models.AdminUser, pw string) error {
+	if err := auth.ValidatePassword(pw, user.Username, user.Email); err != nil {
+		return err
+	}
+	hash, err := models.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	user.PasswordHash = hash
+	user.PasswordChangedAt = &now
+	return nil
+}
+
+// savePassword writes the password columns set by setPassword.
+func savePassword(tx *gorm.DB, user models.AdminUser) error {
+	return tx.Model(&user).Updates(map[string]interface{}{
+		"password_hash":       user.PasswordHash,
+		"password_changed_at": user.PasswordChangedAt,
+		"updated_at":          time.Now(),
+	}).Error
+}
+
+// passwordError answers a failed setPassword.
+func passwordError(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrWeakPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+}
+
+// ChangePassword handles POST /api/v1/auth/password. The caller's other
+// sessions end; the one making the change stays open.
+func ChangePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	var user models.AdminUser
+	if err := config.DB.First(&user, "id = ?", c.GetString("user_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+	// A stolen session must not be able to guess the password here either, so
+	// this shares the login lockout.
+	wait, err := lockedFor(config.DB, userThrottleKey(user.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if wait > 0 {
+		refuseLocked(c, wait)
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		if err := recordLoginFailure(c, &user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current one"})
+		return
+	}
+	if err := setPassword(&user, req.NewPassword); err != nil {
+		passwordError(c, err)
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := savePassword(tx, user); err != nil {
+			return err
+		}
+		return tx.Model(&models.AdminSession{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, c.GetString("session_id")).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": revokedPasswordChanged}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password: " + err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// IssuePasswordReset handles POST /api/v1/admin/users/:id/password-reset. It
+// mails the user a one-time link and voids any link sent before. The token
+// itself is never returned to the SUPERADMIN.
+func IssuePasswordReset(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	var user models.AdminUser
+	row := models.PasswordResetToken{ID: uuid.New(), UserID: uid, TokenHash: hash, ExpiresAt: time.Now().Add(passwordResetTTL), CreatedByID: adminUUID}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", uid).Error; err != nil {
+			return err
+		}
+		if ok, err := outranks(c, user.Role); err != nil {
+			return err
+		} else if !ok {
+			return errOutranked
+		}
+		if err := tx.Model(&models.PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", uid).Update("expires_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&row).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else if errors.Is(err, errOutranked) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot reset the password of a user whose role has permissions you do not hold"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue reset: " + err.Error()})
+		}
+		return
+	}
+
+	link := strings.TrimRight(config.Cfg.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your admin account. Set a new password here within %d minutes:\n\n%s\n\nThe link works once. If you did not expect this, tell your administrator.\n",
+			user.Username, int(passwordResetTTL.Minutes()), link),
+	}
+	err = mail.ErrNotConfigured
+	if Mailer != nil {
+		err = Mailer.Send(c.Request.Context(), msg)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Reset issued but the email failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"sent_to": user.Email, "expires_at": row.ExpiresAt})
+}
+
+// ResetPassword handles POST /api/v1/auth/password/reset with a mailed token.
+// Every session of the user ends and any temporary lockout is lifted.
+func ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	var policyErr error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&token, "token_hash = ?", auth.HashOpaqueToken(req.Token)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return errResetTokenInvalid
+		}
+		var user models.AdminUser
+		if err := tx.First(&user, "id = ?", token.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+		if user.Status == models.StatusInactive {
+			return errResetTokenInvalid
+		}
+		// A weak password leaves the token usable for another try.
+		if policyErr = setPassword(&user, req.NewPassword); policyErr != nil {
+			return nil
+		}
+		if err := savePassword(tx, user); err != nil {
+			return err
+		}
+		if err := tx.Model(&token).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := clearUserFailures(tx, user.ID); err != nil {
+			return err
+		}
+		return revokeSessions(tx, user.ID, revokedPasswordReset)
+	})
+	switch {
+	case errors.Is(err, errResetTokenInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password: " + err.Error()})
+	case policyErr != nil:
+		passwordError(c, policyErr)
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
+	"github.com/ArowuTest/promo-backend/internal/points"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pointsRulesRequest is the JSON payload for a new rule set version. Multipliers
+// are percentages (150 = 1.5x); daily_cap 0 means no cap. effective_from takes
+// RFC 3339 or yyyy-mm-dd and defaults to now.
+type pointsRulesRequest struct {
+	CampaignID         string         `json:"campaign_id,omitempty"`
+	NairaPerPoint      json.Number    `json:"naira_per_point" binding:"required"`
+	ChannelMultipliers map[string]int `json:"channel_multipliers,omitempty"`
+	DayMultipliers     map[string]int `json:"day_multipliers,omitempty"`
+	DailyCap           int            `json:"daily_cap" binding:"gte=0"`
+	EffectiveFrom      string         `json:"effective_from,omitempty"`
+}
+
+func (req pointsRulesRequest) rules() (points.Rules, error) {
+	kobo, err := points.ParseNaira(req.NairaPerPoint.String())
+	if err != nil {
+		return points.Rules{}, errors.New("naira_per_point must be a positive naira value with at most two decimals")
+	}
+	rules := points.Rules{KoboPerPoint: kobo, ChannelMultipliers: req.ChannelMultipliers, DayMultipliers: req.DayMultipliers, DailyCap: req.DailyCap}
+	if err := rules.Validate(); err != nil {
+		return points.Rules{}, err
+	}
+	return rules.Normalize(), nil
+}
+
+// pointsPreviewRequest scores a sample recharge. Rules come from, in order:
+// inline rules, rule_set_id, or whichever rule set ingestion would use at timestamp.
+type pointsPreviewRequest struct {
+	Rules       *pointsRulesRequest `json:"rules,omitempty"`
+	RuleSetID   string              `json:"rule_set_id,omitempty"`
+	MSISDN      string              `json:"msisdn,omitempty"`
+	Amount      json.Number         `json:"amount" binding:"required"`
+	Channel     string              `json:"channel,omitempty"`
+	Timestamp   *time.Time          `json:"timestamp,omitempty"`
+	EarnedToday *int                `json:"earned_today,omitempty"`
+}
+
+func parseEffectiveFrom(s string) (time.Time, error) {
+	if s == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, points.Lagos), nil
+}
+
+// ListPointsRules handles GET /api/v1/points-rules?campaign_id=<uuid|default>
+func ListPointsRules(c *gin.Context) {
+	q := config.DB.Order("effective_from desc, version desc")
+	switch cid := c.Query("campaign_id"); cid {
+	case "":
+	case "default":
+		q = q.Where("campaign_id IS NULL")
+	default:
+		id, err := uuid.Parse(cid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+			return
+		}
+		q = q.Where("campaign_id = ?", id)
+	}
+	var sets []models.PointsRuleSet
+	if err := q.Find(&sets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch points rules: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sets)
+}
+
+// GetPointsRules handles GET /api/v1/points-rules/:id
+func GetPointsRules(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule set ID"})
+		return
+	}
+	var rs models.PointsRuleSet
+	if err := config.DB.First(&rs, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule set not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, rs)
+}
+
+// CreatePointsRules handles POST /api/v1/points-rules
+//
+// Rule sets are never edited: each change is saved as the next version for its
+// campaign, so points already frozen in the ledger stay traceable to their rules.
+func CreatePointsRules(c *gin.Context) {
+	var req pointsRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	rules, err := req.rules()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	effectiveFrom, err := parseEffectiveFrom(req.EffectiveFrom)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective_from; use RFC 3339 or yyyy-mm-dd"})
+		return
+	}
+	campaignID, msg := lookupCampaignID(req.CampaignID)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	adminIDStr, _ := c.Get("user_id")
+	adminUUID, _ := uuid.Parse(adminIDStr.(string))
+	rs := models.PointsRuleSet{
+		ID:                 uuid.New(),
+		CampaignID:         campaignID,
+		KoboPerPoint:       rules.KoboPerPoint,
+		ChannelMultipliers: rules.ChannelMultipliers,
+		DayMultipliers:     rules.DayMultipliers,
+		DailyCap:           rules.DailyCap,
+		EffectiveFrom:      effectiveFrom,
+		CreatedByID:        adminUUID,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&models.PointsRuleSet{})
+		if campaignID == nil {
+			q = q.Where("campaign_id IS NULL")
+		} else {
+			q = q.Where("campaign_id = ?", *campaignID)
+		}
+		var latest int
+		if err := q.Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		rs.Version = latest + 1
+		return tx.Create(&rs).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save points rules: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rs)
+}
+
+// PreviewPoints handles POST /api/v1/points-rules/preview and shows how a sample
+// recharge would score without storing anything.
+func PreviewPoints(c *gin.Context) {
+	var req pointsPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	kobo, err := points.ParseNaira(req.Amount.String())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	at := time.Now()
+	if req.Timestamp != nil {
+		at = *req.Timestamp
+	}
+
+	scorer, err := ledger.NewScorer(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load points rules: " + err.Error()})
+		return
+	}
+	var rs *models.PointsRuleSet
+	var rules points.Rules
+	switch {
+	case req.Rules != nil:
+		if rules, err = req.Rules.rules(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	case req.RuleSetID != "":
+		id, err := uuid.Parse(req.RuleSetID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule set ID"})
+			return
+		}
+		rs = &models.PointsRuleSet{}
+		if err := config.DB.First(rs, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule set not found"})
+			return
+		}
+		rules = ledger.RulesOf(rs)
+	default:
+		rs = scorer.RuleSetAt(at)
+		rules = ledger.RulesOf(rs)
+	}
+
+	earned := 0
+	switch {
+	case req.EarnedToday != nil:
+		earned = *req.EarnedToday
+	case req.MSISDN != "":
+		number, err := msisdn.Normalize(req.MSISDN)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if earned, err = scorer.EarnedOn(number, points.DayStart(at)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule_set": rs,
+		"rules":    rules,
+		"score":    rules.Score(kobo, req.Channel, at, earned),
+	})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPoolTopN = 10
+	maxPoolTopN     = 100
+)
+
+// anomalyThresholds applies the optional threshold overrides in the query
+// (outlier_factor, max_entry_points, max_top_share, jump_ratio) to the defaults.
+func anomalyThresholds(c *gin.Context) (ledger.AnomalyThresholds, error) {
+	th := ledger.DefaultAnomalyThresholds
+	floats := map[string]*float64{"outlier_factor": &th.OutlierFactor, "max_top_share": &th.MaxTopShare, "jump_ratio": &th.JumpRatio}
+	for name, dst := range floats {
+		if v := c.Query(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				return th, errors.New("Invalid " + name)
+			}
+			*dst = f
+		}
+	}
+	if v := c.Query("max_entry_points"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return th, errors.New("Invalid max_entry_points")
+		}
+		th.MaxEntryPoints = n
+	}
+	return th, nil
+}
+
+// previousDrawStats finds the latest draw before the given date, on the same
+// weekday, whose pool can be rebuilt exactly, that is one drawn from an
+// ingested file or from a pinned ledger window, and returns it with its pool
+// statistics. Entry windows differ in length by weekday (Saturday's covers the
+// week, Tuesday's one day), so only draws on the same weekday are comparable.
+// It returns nil when there is no such draw or no date to compare against.
+func previousDrawStats(before *time.Time) (*models.Draw, *ledger.PoolStats, error) {
+	if before == nil {
+		return nil, nil, nil
+	}
+	q := config.DB.Where("ingestion_job_id IS NOT NULL OR ledger_cutoff IS NOT NULL").
+		Where("draw_date < ? AND EXTRACT(DOW FROM draw_date AT TIME ZONE 'UTC') = ?", *before, int(before.Weekday()))
+	var prev models.Draw
+	if err := q.Order("draw_date desc, created_at desc").First(&prev).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	var stats *ledger.PoolStats
+	var err error
+	if prev.IngestionJobID != nil {
+		stats, err = ledger.JobStats(config.DB, *prev.IngestionJobID, 0)
+	} else {
+		stats, err = ledger.WindowStats(config.DB, *prev.WindowStart, *prev.WindowEnd, *prev.LedgerCutoff, 0)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &prev, stats, nil
+}
+
+// GetPoolStats handles GET /api/v1/ledger/pool/stats?draw_date=yyyy-mm-dd or
+// ?ingestion_job_id=<uuid>, with optional top=N and anomaly threshold overrides.
+//
+// It describes the pool a draw would select from, the ledger window for the
+// draw date or the rows of one loaded file, and flags anomalies within it and
+// against the previous draw on the same weekday, so operators can check the
+// pool before approving.
+func GetPoolStats(c *gin.Context) {
+	topN := defaultPoolTopN
+	if v := c.Query("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxPoolTopN {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must be between 0 and " + strconv.Itoa(maxPoolTopN)})
+			return
+		}
+		topN = n
+	}
+	th, err := anomalyThresholds(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var drawDate *time.Time
+	if d := c.Query("draw_date"); d != "" {
+		parsed, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draw_date; use yyyy-mm-dd"})
+			return
+		}
+		drawDate = &parsed
+	}
+
+	resp := gin.H{}
+	var stats *ledger.PoolStats
+	if raw := c.Query("ingestion_job_id"); raw != "" {
+		var checkDate time.Time
+		if drawDate != nil {
+			checkDate = *drawDate
+		}
+		job, msg := lookupPoolJob(raw, checkDate)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if drawDate == nil {
+			drawDate = job.DrawDate
+		}
+		resp["ingestion_job_id"] = job.ID
+		stats, err = ledger.JobStats(config.DB, job.ID, topN)
+	} else {
+		if drawDate == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Give draw_date (yyyy-mm-dd) or ingestion_job_id"})
+			return
+		}
+		windowStart, windowEnd, werr := computeDrawWindow(*drawDate)
+		if werr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + werr.Error()})
+			return
+		}
+		cutoff := time.Now()
+		resp["window_start"], resp["window_end"], resp["cutoff"] = windowStart, windowEnd, cutoff
+		stats, err = ledger.WindowStats(config.DB, windowStart, windowEnd, cutoff, topN)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()})
+		return
+	}
+
+	prevDraw, prevStats, err := previousDrawStats(drawDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load previous draw: " + err.Error()})
+		return
+	}
+	resp["draw_date"] = drawDate
+	resp["stats"] = stats
+	resp["thresholds"] = th
+	resp["anomalies"] = ledger.DetectAnomalies(stats, prevStats, th)
+	if prevDraw != nil {
+		resp["previous_draw"] = gin.H{"draw_id": prevDraw.ID, "draw_date": prevDraw.DrawDate, "entries": prevStats.Entries, "total_points": prevStats.TotalPoints}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -1,45 +1,209 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/ArowuTest/promo-backend/internal/calendar"
 	"github.com/ArowuTest/promo-backend/internal/config"
 	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rng"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 type prizeStructureRequest struct {
 	Name         string   `json:"name" binding:"required"`
+	CampaignID   string   `json:"campaign_id,omitempty"`
 	Effective    string   `json:"effective" binding:"required"`
+	EndDate      string   `json:"end_date,omitempty"`
+	Priority     int      `json:"priority" binding:"gte=0"`
 	EligibleDays []string `json:"eligible_days" binding:"required,min=1"`
+	// WeightStrategy defaults to LINEAR; WeightCap is required for CAPPED.
+	WeightStrategy string `json:"weight_strategy,omitempty"`
+	WeightCap      int    `json:"weight_cap" binding:"gte=0"`
 	Tiers        []struct {
-		TierName      string `json:"tier_name" binding:"required"`
-		Amount        int    `json:"amount" binding:"required,gte=0"`
-		Quantity      int    `json:"quantity" binding:"required,gte=1"`
-		RunnerUpCount int    `json:"runner_up_count" binding:"required,gte=0"`
-		OrderIndex    int    `json:"order_index" binding:"required,gte=1"`
+		TierName      string   `json:"tier_name" binding:"required"`
+		Amount        int      `json:"amount" binding:"required,gte=0"`
+		Quantity      int      `json:"quantity" binding:"required,gte=1"`
+		RunnerUpCount int      `json:"runner_up_count" binding:"required,gte=0"`
+		OrderIndex    int      `json:"order_index" binding:"required,gte=1"`
+		MinPoints     int      `json:"min_points" binding:"gte=0"`
+		MinTenureDays int      `json:"min_tenure_days" binding:"gte=0"`
+		Segments      []string `json:"segments,omitempty"`
+		Regions       []string `json:"regions,omitempty"`
+		ProductTypes  []string `json:"product_types,omitempty"`
 	} `json:"tiers" binding:"required,min=1,dive"`
 }
 
+var validWeekdays = map[string]bool{
+	"Sunday": true, "Monday": true, "Tuesday": true, "Wednesday": true,
+	"Thursday": true, "Friday": true, "Saturday": true,
+}
+
+// parseStructureDates validates the effective/end dates and eligible days of a request.
+func parseStructureDates(req prizeStructureRequest) (time.Time, *time.Time, string) {
+	effDate, err := time.Parse("2006-01-02", req.Effective)
+	if err != nil {
+		return time.Time{}, nil, "Invalid effective date; use yyyy-MM-dd"
+	}
+	var endDate *time.Time
+	if req.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return time.Time{}, nil, "Invalid end date; use yyyy-MM-dd"
+		}
+		if parsed.Before(effDate) {
+			return time.Time{}, nil, "End date cannot be before the effective date"
+		}
+		endDate = &parsed
+	}
+	for _, d := range req.EligibleDays {
+		if !validWeekdays[d] {
+			return time.Time{}, nil, "Invalid eligible day: " + d
+		}
+	}
+	if err := structureWeighting(req).Validate(); err != nil {
+		return time.Time{}, nil, err.Error()
+	}
+	return effDate, endDate, ""
+}
+
+func structureWeighting(req prizeStructureRequest) rng.Weighting {
+	w := rng.Weighting{Strategy: rng.WeightStrategy(req.WeightStrategy), Cap: req.WeightCap}
+	if w.Strategy == "" {
+		w.Strategy = rng.WeightLinear
+	}
+	return w
+}
+
+// lookupCampaignID parses and verifies the optional campaign a structure belongs to.
+func lookupCampaignID(raw string) (*uuid.UUID, string) {
+	if raw == "" {
+		return nil, ""
+	}
+	cid, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, "Invalid campaign ID"
+	}
+	if err := config.DB.First(&models.Campaign{}, "id = ?", cid).Error; err != nil {
+		return nil, "Campaign not found"
+	}
+	return &cid, ""
+}
+
+// prizeStructureLockKey names the advisory lock held while a structure is
+// checked for overlaps and saved, so two concurrent writes cannot both pass
+// the check.
+const prizeStructureLockKey = 7410242
+
+// lockPrizeStructures takes the overlap lock until tx ends.
+func lockPrizeStructures(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", prizeStructureLockKey).Error
+}
+
+// findOverlappingStructures returns structures with the same priority whose
+// effective range and eligible days intersect the given ones.
+func findOverlappingStructures(db *gorm.DB, excludeID uuid.UUID, effDate time.Time, endDate *time.Time, days []string, priority int) ([]models.PrizeStructure, error) {
+	q := db.Model(&models.PrizeStructure{}).
+		Where("priority = ?", priority).
+		Where("eligible_days && ?", pq.StringArray(days)).
+		Where("(end_date IS NULL OR end_date >= ?)", effDate)
+	if endDate != nil {
+		q = q.Where("effective <= ?", *endDate)
+	}
+	if excludeID != uuid.Nil {
+		q = q.Where("id <> ?", excludeID)
+	}
+	var overlapping []models.PrizeStructure
+	err := q.Order("effective asc").Find(&overlapping).Error
+	return overlapping, err
+}
+
+func overlapConflictResponse(overlapping []models.PrizeStructure) gin.H {
+	var conflicts []gin.H
+	for _, ps := range overlapping {
+		conflicts = append(conflicts, gin.H{"id": ps.ID, "name": ps.Name, "effective": ps.Effective, "end_date": ps.EndDate, "eligible_days": ps.EligibleDays, "priority": ps.Priority})
+	}
+	return gin.H{"error": "Prize structure overlaps existing structures with the same priority", "conflicts": conflicts}
+}
+
+var errNoDrawOnDate = errors.New("no draw may run on this date")
+
+// resolvePrizeStructure deterministically picks the single structure that applies on date:
+// highest priority first, then the most recently effective, then the oldest record.
+// Holidays and blackouts resolve to errNoDrawOnDate; an extra draw date uses its
+// pinned structure, or otherwise any structure in effect regardless of weekday.
+func resolvePrizeStructure(date time.Time) (*models.PrizeStructure, error) {
+	cal, err := calendar.Load(config.DB, date)
+	if err != nil {
+		return nil, err
+	}
+	if _, blocked := cal.NoDrawEntry(date); blocked {
+		return nil, errNoDrawOnDate
+	}
+
+	q := config.DB.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("prize_tiers.order_index asc")
+	})
+	extra, isExtra := cal.ExtraDrawEntry(date)
+	switch {
+	case isExtra && extra.PrizeStructureID != nil:
+		q = q.Where("id = ?", *extra.PrizeStructureID)
+	case isExtra:
+		q = q.Where("effective <= ? AND (end_date IS NULL OR end_date >= ?)", date, date)
+	default:
+		q = q.Where("effective <= ? AND (end_date IS NULL OR end_date >= ?) AND ? = ANY(eligible_days)", date, date, date.Weekday().String())
+	}
+
+	var ps models.PrizeStructure
+	if err := q.Order("priority desc, effective desc, created_at asc, id asc").First(&ps).Error; err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
 func CreatePrizeStructure(c *gin.Context) {
 	var req prizeStructureRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()}); return
 	}
-	effDate, err := time.Parse("2006-01-02", req.Effective)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective date; use yyyy-MM-dd"}); return
+	effDate, endDate, msg := parseStructureDates(req)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg}); return
+	}
+	campaignID, msg := lookupCampaignID(req.CampaignID)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg}); return
 	}
 	var tiers []models.PrizeTier
 	for _, t := range req.Tiers {
-		tiers = append(tiers, models.PrizeTier{ID: uuid.New(), TierName: t.TierName, Amount: t.Amount, Quantity: t.Quantity, RunnerUpCount: t.RunnerUpCount, OrderIndex: t.OrderIndex})
+		tiers = append(tiers, models.PrizeTier{ID: uuid.New(), TierName: t.TierName, Amount: t.Amount, Quantity: t.Quantity, RunnerUpCount: t.RunnerUpCount, OrderIndex: t.OrderIndex,
+			MinPoints: t.MinPoints, MinTenureDays: t.MinTenureDays, Segments: t.Segments, Regions: t.Regions, ProductTypes: t.ProductTypes})
+	}
+	ps := models.PrizeStructure{ID: uuid.New(), Name: req.Name, CampaignID: campaignID, Effective: effDate, EndDate: endDate, Priority: req.Priority, EligibleDays: req.EligibleDays, Tiers: tiers}
+	weighting := structureWeighting(req)
+	ps.WeightStrategy, ps.WeightCap = string(weighting.Strategy), weighting.Cap
+
+	tx := config.DB.Begin()
+	if err := lockPrizeStructures(tx); err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()}); return
+	}
+	overlapping, err := findOverlappingStructures(tx, uuid.Nil, effDate, endDate, req.EligibleDays, req.Priority)
+	if err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking overlaps: " + err.Error()}); return
+	}
+	if len(overlapping) > 0 {
+		tx.Rollback(); c.JSON(http.StatusConflict, overlapConflictResponse(overlapping)); return
 	}
-	ps := models.PrizeStructure{ID: uuid.New(), Name: req.Name, Effective: effDate, EligibleDays: req.EligibleDays, Tiers: tiers}
-	if err := config.DB.Create(&ps).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prize structure: " + err.Error()}); return
+	if err := tx.Create(&ps).Error; err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prize structure: " + err.Error()}); return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit error"}); return
 	}
 	c.JSON(http.StatusCreated, ps)
 }
@@ -74,8 +238,8 @@ func ListPrizeStructures(c *gin.Context) {
 			Preload("Tiers", func(db *gorm.DB) *gorm.DB {
 				return db.Order("prize_tiers.order_index asc")
 			}).
-			Where("effective <= ? AND ? = ANY(eligible_days)", parsedDate, dayOfWeek).
-			Order("effective desc").
+			Where("effective <= ? AND (end_date IS NULL OR end_date >= ?) AND ? = ANY(eligible_days)", parsedDate, parsedDate, dayOfWeek).
+			Order("priority desc, effective desc").
 			Find(&validStructures).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error fetching valid structures: " + err.Error()}); return
 		}
@@ -95,24 +259,58 @@ func ListPrizeStructures(c *gin.Context) {
 	c.JSON(http.StatusOK, all)
 }
 
+// ResolvePrizeStructure handles GET /api/v1/prize-structures/resolve?date=yyyy-mm-dd
+func ResolvePrizeStructure(c *gin.Context) {
+	dateQuery := c.Query("date")
+	if dateQuery == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "date query parameter is required"}); return }
+	parsedDate, err := time.Parse("2006-01-02", dateQuery)
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format; use yyyy-mm-dd"}); return }
+	ps, err := resolvePrizeStructure(parsedDate)
+	if err != nil {
+		if err == errNoDrawOnDate { c.JSON(http.StatusConflict, gin.H{"error": "No draw may run on this date (holiday or blackout)"})
+		} else if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "No prize structure applies to this date"})
+		} else { c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()}) }
+		return
+	}
+	c.JSON(http.StatusOK, ps)
+}
+
 func UpdatePrizeStructure(c *gin.Context) {
 	idParam := c.Param("id")
 	pid, err := uuid.Parse(idParam)
 	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prize structure ID"}); return }
 	var req prizeStructureRequest
 	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()}); return }
-	effDate, err := time.Parse("2006-01-02", req.Effective)
-	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective date; use yyyy-MM-dd"}); return }
+	effDate, endDate, msg := parseStructureDates(req)
+	if msg != "" { c.JSON(http.StatusBadRequest, gin.H{"error": msg}); return }
+	campaignID, msg := lookupCampaignID(req.CampaignID)
+	if msg != "" { c.JSON(http.StatusBadRequest, gin.H{"error": msg}); return }
 	
 	tx := config.DB.Begin()
+	if err := lockPrizeStructures(tx); err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()}); return
+	}
 	var existing models.PrizeStructure
 	if err := tx.First(&existing, "id = ?", pid).Error; err != nil {
 		tx.Rollback(); c.JSON(http.StatusNotFound, gin.H{"error": "Prize structure not found"}); return
 	}
 
+	overlapping, err := findOverlappingStructures(tx, pid, effDate, endDate, req.EligibleDays, req.Priority)
+	if err != nil {
+		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking overlaps: " + err.Error()}); return
+	}
+	if len(overlapping) > 0 {
+		tx.Rollback(); c.JSON(http.StatusConflict, overlapConflictResponse(overlapping)); return
+	}
+
 	existing.Name = req.Name
+	existing.CampaignID = campaignID
 	existing.Effective = effDate
+	existing.EndDate = endDate
+	existing.Priority = req.Priority
 	existing.EligibleDays = req.EligibleDays
+	weighting := structureWeighting(req)
+	existing.WeightStrategy, existing.WeightCap = string(weighting.Strategy), weighting.Cap
 
 	if err := tx.Save(&existing).Error; err != nil {
 		tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prize structure details"}); return
@@ -123,7 +321,8 @@ func UpdatePrizeStructure(c *gin.Context) {
 	}
 
 	for _, t := range req.Tiers {
-		newTier := models.PrizeTier{ID: uuid.New(), PrizeStructureID: pid, TierName: t.TierName, Amount: t.Amount, Quantity: t.Quantity, RunnerUpCount: t.RunnerUpCount, OrderIndex: t.OrderIndex}
+		newTier := models.PrizeTier{ID: uuid.New(), PrizeStructureID: pid, TierName: t.TierName, Amount: t.Amount, Quantity: t.Quantity, RunnerUpCount: t.RunnerUpCount, OrderIndex: t.OrderIndex,
+			MinPoints: t.MinPoints, MinTenureDays: t.MinTenureDays, Segments: t.Segments, Regions: t.Regions, ProductTypes: t.ProductTypes}
 		if err := tx.Create(&newTier).Error; err != nil {
 			tx.Rollback(); c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create new tier"}); return
 		}
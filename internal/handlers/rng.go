@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ArowuTest/promo-backend/internal/rng"
+	"github.com/gin-gonic/gin"
+)
+
+// RNGHealth handles GET /api/v1/rng/health
+func RNGHealth(c *gin.Context) {
+	status := rng.Health()
+	code := http.StatusOK
+	if !status.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, status)
+}
+
+// RecoverRNG handles POST /api/v1/rng/recover, unblocking draws after a health-test failure.
+func RecoverRNG(c *gin.Context) {
+	if err := rng.Recover(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "RNG self-test failed: " + err.Error(), "health": rng.Health()})
+		return
+	}
+	c.JSON(http.StatusOK, rng.Health())
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// roleNamePattern keeps role names in the style of the built-in ones.
+var roleNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,31}$`)
+
+// errRoleInUse refuses deleting a role that users still hold.
+var errRoleInUse = errors.New("role in use")
+
+// errOutranked refuses an action on a user whose role has permissions the caller does not hold.
+var errOutranked = errors.New("user outranks caller")
+
+// roleRequest is the JSON payload for creating a role. Updates ignore Name.
+type roleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// grantableRole loads role and checks the caller holds every permission it
+// grants, so nobody can raise their own or anyone else's access.
+func grantableRole(c *gin.Context, role models.AdminUserRole) (int, gin.H) {
+	var r models.Role
+	if err := config.DB.First(&r, "name = ?", role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusBadRequest, gin.H{"error": "Unknown role " + string(role)}
+		}
+		return http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()}
+	}
+	if !permissions(c).Covers(rbac.NewSet(r.Permissions)) {
+		return http.StatusForbidden, gin.H{"error": "Role " + string(role) + " has permissions you do not hold"}
+	}
+	return 0, nil
+}
+
+// outranks reports whether the caller holds every permission of role. A role
+// that no longer exists grants nothing and is always outranked.
+func outranks(c *gin.Context, role models.AdminUserRole) (bool, error) {
+	perms, err := rbac.Load(config.DB, role)
+	if err != nil {
+		return false, err
+	}
+	return permissions(c).Covers(perms), nil
+}
+
+// roleResponse adds the number of users holding r.
+func roleResponse(r models.Role, users int64) gin.H {
+	return gin.H{
+		"name":          r.Name,
+		"description":   r.Description,
+		"permissions":   rbac.NewSet(r.Permissions).List(),
+		"built_in":      r.BuiltIn,
+		"users":         users,
+		"updated_by_id": r.UpdatedByID,
+		"updated_at":    r.UpdatedAt,
+	}
+}
+
+// ListPermissions handles GET /api/v1/roles/permissions
+func ListPermissions(c *gin.Context) {
+	resp := make([]gin.H, 0, len(rbac.Catalogue))
+	for _, p := range rbac.All() {
+		resp = append(resp, gin.H{"permission": p, "description": rbac.Catalogue[p]})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListRoles handles GET /api/v1/roles
+func ListRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.Order("name asc").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles: " + err.Error()})
+		return
+	}
+	var counts []struct {
+		Role  models.AdminUserRole
+		Users int64
+	}
+	if err := config.DB.Model(&models.AdminUser{}).Select("role, COUNT(*) AS users").Group("role").Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users: " + err.Error()})
+		return
+	}
+	users := make(map[models.AdminUserRole]int64, len(counts))
+	for _, n := range counts {
+		users[n.Role] = n.Users
+	}
+	resp := make([]gin.H, 0, len(roles))
+	for _, r := range roles {
+		resp = append(resp, roleResponse(r, users[r.Name]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateRole handles POST /api/v1/roles
+func CreateRole(c *gin.Context) {
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	name := strings.ToUpper(strings.TrimSpace(req.Name))
+	if !roleNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name must be 2-32 capital letters, digits or underscores"})
+		return
+	}
+	if err := rbac.Validate(req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !permissions(c).Covers(rbac.NewSet(req.Permissions)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot grant permissions you do not hold"})
+		return
+	}
+	adminUUID, _ := uuid.Parse(c.GetString("user_id"))
+
+	role := models.Role{
+		Name:        models.AdminUserRole(name),
+		Description: req.Description,
+		Permissions: pq.StringArray(req.Permissions),
+		UpdatedByID: &adminUUID,
+	}
+	res := config.DB.Where("name = ?", role.Name).FirstOrCreate(&role)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role: " + res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+	c.JSON(http.StatusCreated, roleResponse(role, 0))
+}
+
+// UpdateRole handles PUT /api/v1/roles/:name. It replaces the description and
+// permissions; users holding the role are affected on their next request.
+func UpdateRole(c *gin.Context) {
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	if err := rbac.Validate(req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.Role
+	if err := config.DB.First(&role, "name = ?", c.Param("name")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		}
+		return
+	}
+	if role.BuiltIn {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in role " + string(role.Name) + " cannot be edited"})
+		return
+	}
+	// Both what the role had and what it gets must be within the caller's reach.
+	mine := permissions(c)
+	if !mine.Covers(rbac.NewSet(role.Permissions)) || !mine.Covers(rbac.NewSet(req.Permissions)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot change a role beyond the permissions you hold"})
+		return
+	}
+	adminUUID, _ := uuid.Parse(c.GetString("user_id"))
+
+	role.Description = req.Description
+	role.Permissions = pq.StringArray(req.Permissions)
+	role.UpdatedByID = &adminUUID
+	if err := config.DB.Save(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role: " + err.Error()})
+		return
+	}
+	var users int64
+	config.DB.Model(&models.AdminUser{}).Where("role = ?", role.Name).Count(&users)
+	c.JSON(http.StatusOK, roleResponse(role, users))
+}
+
+// DeleteRole handles DELETE /api/v1/roles/:name. Roles still held by a user
+// cannot be deleted.
+func DeleteRole(c *gin.Context) {
+	var role models.Role
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&role, "name = ?", c.Param("name")).Error; err != nil {
+			return err
+		}
+		if role.BuiltIn {
+			return nil
+		}
+		var users int64
+		if err := tx.Model(&models.AdminUser{}).Where("role = ?", role.Name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return errRoleInUse
+		}
+		return tx.Delete(&role).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case errors.Is(err, errRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is assigned to users; move them to another role first"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role: " + err.Error()})
+	case role.BuiltIn:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in role " + string(role.Name) + " cannot be deleted"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/auth"
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons recorded when a session is revoked.
+const (
+	revokedLogout          = "LOGOUT"
+	revokedLogoutAll       = "LOGOUT_ALL"
+	revokedTokenReuse      = "REFRESH_TOKEN_REUSE"
+	revokedUserChanged     = "USER_DEACTIVATED"
+	revokedUserDeleted     = "USER_DELETED"
+	revokedMFAReset        = "MFA_RESET"
+	revokedPasswordChanged = "PASSWORD_CHANGED"
+	revokedPasswordReset   = "PASSWORD_RESET"
+)
+
+// refreshRequest is the JSON payload for /auth/refresh.
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// logoutRequest is the optional JSON payload for /auth/logout; all ends every
+// session of the user instead of only the current one.
+type logoutRequest struct {
+	All bool `json:"all"`
+}
+
+// errSessionInvalid rejects a refresh token that is unknown, expired, spent or
+// belongs to a revoked session or inactive user.
+var errSessionInvalid = errors.New("session invalid")
+
+// issueTokens stores a new refresh token for session and signs an access
+// token for user under it, returning the login response body.
+func issueTokens(tx *gorm.DB, user models.AdminUser, session models.AdminSession) (gin.H, error) {
+	refresh, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	row := models.RefreshToken{ID: uuid.New(), SessionID: session.ID, TokenHash: hash, ExpiresAt: time.Now().Add(config.Cfg.RefreshTokenTTL)}
+	if err := tx.Create(&row).Error; err != nil {
+		return nil, err
+	}
+	perms, err := rbac.Load(tx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	access, err := auth.GenerateJWT(user.ID.String(), user.Username, string(user.Role), session.ID.String(), config.Cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":              access,
+		"expires_in":         int(config.Cfg.AccessTokenTTL.Seconds()),
+		"refresh_token":      refresh,
+		"refresh_expires_at": row.ExpiresAt,
+		"user_id":            user.ID.String(),
+		"username":           user.Username,
+		"role":               user.Role,
+		"permissions":        perms.List(),
+	}, nil
+}
+
+// startSession opens a session for user and returns its first tokens.
+func startSession(c *gin.Context, user models.AdminUser) (gin.H, error) {
+	var body gin.H
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session := models.AdminSession{ID: uuid.New(), UserID: user.ID, IP: c.ClientIP(), UserAgent: c.Request.UserAgent(), CreatedAt: now, LastUsedAt: now}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		body, err = issueTokens(tx, user, session)
+		return err
+	})
+	return body, err
+}
+
+// revokeSession ends one session.
+func revokeSession(db *gorm.DB, sessionID uuid.UUID, reason string) error {
+	return db.Model(&models.AdminSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// revokeSessions ends every open session of userID.
+func revokeSessions(db *gorm.DB, userID uuid.UUID, reason string) error {
+	return db.Model(&models.AdminSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// RefreshSession handles POST /api/v1/auth/refresh
+//
+// The refresh token is spent and replaced, so each one works once. Presenting
+// a spent token means it was copied, and the whole session is revoked.
+func RefreshSession(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+
+	var body gin.H
+	var reused *uuid.UUID
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&token, "token_hash = ?", auth.HashOpaqueToken(req.RefreshToken)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errSessionInvalid
+			}
+			return err
+		}
+		var session models.AdminSession
+		if err := tx.First(&session, "id = ?", token.SessionID).Error; err != nil {
+			return err
+		}
+		if session.RevokedAt != nil {
+			return errSessionInvalid
+		}
+		now := time.Now()
+		if token.UsedAt != nil {
+			reused = &session.ID
+			return nil
+		}
+		if now.After(token.ExpiresAt) {
+			return errSessionInvalid
+		}
+		var user models.AdminUser
+		if err := tx.First(&user, "id = ?", session.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errSessionInvalid
+			}
+			return err
+		}
+		if user.Status != models.StatusActive {
+			return errSessionInvalid
+		}
+
+		token.UsedAt = &now
+		if err := tx.Save(&token).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&session).Update("last_used_at", now).Error; err != nil {
+			return err
+		}
+		var err error
+		body, err = issueTokens(tx, user, session)
+		return err
+	})
+	if err == nil && reused != nil {
+		err = revokeSession(config.DB, *reused, revokedTokenReuse)
+		if err == nil {
+			err = errSessionInvalid
+		}
+	}
+	switch {
+	case errors.Is(err, errSessionInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, body)
+	}
+}
+
+// Logout handles POST /api/v1/auth/logout and revokes the caller's session,
+// or all of their sessions when "all" is true.
+func Logout(c *gin.Context) {
+	var req logoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+			return
+		}
+	}
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	sessionID, _ := uuid.Parse(c.GetString("session_id"))
+
+	var err error
+	if req.All {
+		err = revokeSessions(config.DB, userID, revokedLogoutAll)
+	} else {
+		err = revokeSession(config.DB, sessionID, revokedLogout)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out: " + err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
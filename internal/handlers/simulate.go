@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/ledger"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rng"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSimulationIterations = 10000
+	// maxSimulationPool caps the entry rows a request may submit or load. It
+	// does not bound the work on its own; rng.MaxIterationsFor lowers the
+	// iteration limit as the pool grows.
+	maxSimulationPool = 10000
+)
+
+// simulateRequest is the JSON payload for /draws/simulate. Without a prize
+// structure the pool is drawn as a single one-winner tier with linear weights;
+// without msisdn_entries the pool is fetched for draw_date like a live draw.
+type simulateRequest struct {
+	PrizeStructureID string        `json:"prize_structure_id,omitempty"`
+	DrawDate         string        `json:"draw_date,omitempty"`
+	Iterations       int           `json:"iterations" binding:"gte=0"`
+	MSISDNEntries    []MSISDNEntry `json:"msisdn_entries,omitempty" binding:"dive"`
+}
+
+// SimulateDraw handles POST /api/v1/draws/simulate. Nothing is persisted.
+func SimulateDraw(c *gin.Context) {
+	var req simulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	if req.Iterations > rng.MaxSimulationIterations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many iterations requested"})
+		return
+	}
+
+	tiers := []models.PrizeTier{{ID: uuid.New(), TierName: "Simulated", Quantity: 1, OrderIndex: 1}}
+	weighting := rng.LinearWeighting
+	if req.PrizeStructureID != "" {
+		pid, err := uuid.Parse(req.PrizeStructureID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Prize Structure ID format"})
+			return
+		}
+		var ps models.PrizeStructure
+		if err := config.DB.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index asc")
+		}).First(&ps, "id = ?", pid).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Selected prize structure not found"})
+			return
+		}
+		tiers = ps.Tiers
+		weighting = rng.Weighting{Strategy: rng.WeightStrategy(ps.WeightStrategy), Cap: ps.WeightCap}
+	}
+
+	var entries []models.EligibleEntry
+	if len(req.MSISDNEntries) > 0 {
+		for _, row := range req.MSISDNEntries {
+			entries = append(entries, models.EligibleEntry{MSISDN: row.MSISDN, Points: row.Points, Segment: row.Segment, Region: row.Region, TenureDays: row.TenureDays, ProductType: row.ProductType})
+		}
+	} else {
+		if req.DrawDate == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Supply msisdn_entries or a draw_date to load the stored pool"})
+			return
+		}
+		if failure := ledgerTierFilters(models.PrizeStructure{Tiers: tiers}); failure != nil {
+			c.JSON(http.StatusBadRequest, failure)
+			return
+		}
+		drawDate, err := time.Parse("2006-01-02", req.DrawDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format; use yyyy-mm-dd"})
+			return
+		}
+		windowStart, windowEnd, err := computeDrawWindow(drawDate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load draw calendar: " + err.Error()})
+			return
+		}
+		entries, err = ledger.Pool(config.DB, windowStart, windowEnd, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read recharge ledger: " + err.Error()})
+			return
+		}
+	}
+
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No eligible entries to simulate"})
+		return
+	}
+	if len(entries) > maxSimulationPool {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pool too large to simulate"})
+		return
+	}
+	// There are at least as many rows as subscribers, so a run allowed here
+	// is never refused by rng.Simulate.
+	maxIterations := rng.MaxIterationsFor(len(entries))
+	iterations := req.Iterations
+	if iterations == 0 {
+		iterations = min(defaultSimulationIterations, maxIterations)
+	}
+	if iterations > maxIterations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many iterations for a pool of this size", "max_iterations": maxIterations})
+		return
+	}
+
+	result, err := rng.Simulate(entries, tiers, weighting, iterations)
+	if err != nil {
+		if errors.Is(err, rng.ErrInvalidTierConfig) || errors.Is(err, rng.ErrRNGFailure) {
+			c.JSON(drawErrorResponse("Simulation failed", err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Simulation failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
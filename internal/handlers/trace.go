@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rng"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// saveSelectionTrace persists every pick of a draw inside the draw's transaction.
+func saveSelectionTrace(tx *gorm.DB, drawID uuid.UUID, trace *rng.SelectionTrace) error {
+	if trace == nil || len(trace.Steps) == 0 {
+		return nil
+	}
+	rows := make([]models.DrawSelectionStep, 0, len(trace.Steps))
+	for _, st := range trace.Steps {
+		rows = append(rows, models.DrawSelectionStep{
+			ID:          uuid.New(),
+			DrawID:      drawID,
+			Seq:         st.Seq,
+			TierName:    st.TierName,
+			IsRunnerUp:  st.IsRunnerUp,
+			RandomWord:  strconv.FormatUint(st.RandomWord, 16),
+			TotalWeight: int64(st.TotalWeight),
+			Target:      int64(st.Target),
+			CumIndex:    st.Index,
+			MSISDN:      st.MSISDN,
+			Outcome:     string(st.Outcome),
+		})
+	}
+	return tx.CreateInBatches(&rows, 500).Error
+}
+
+// GetDrawTrace handles GET /api/v1/draws/:id/trace
+func GetDrawTrace(c *gin.Context) {
+	drawID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draw ID format"})
+		return
+	}
+
+	var draw models.Draw
+	if err := config.DB.First(&draw, "id = ?", drawID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Draw not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error fetching draw"})
+		}
+		return
+	}
+
+	var steps []models.DrawSelectionStep
+	if err := config.DB.Where("draw_id = ?", drawID).Order("seq asc").Find(&steps).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch selection trace: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"draw_id":         draw.ID,
+		"draw_date":       draw.DrawDate,
+		"weight_strategy": draw.WeightStrategy,
+		"steps":           steps,
+	})
+}
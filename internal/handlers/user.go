@@ -8,16 +8,16 @@ import (
 	"github.com/ArowuTest/promo-backend/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// userRequest represents the JSON payload for creating/updating a user.
+// userRequest represents the JSON payload for creating/updating a user. Role
+// must name a stored role.
 type userRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password,omitempty"`
-	Role     string `json:"role" binding:"required,oneof=SUPERADMIN ADMIN SENIORUSER WINNERREPORTS ALLREPORTS"`
+	Role     string `json:"role" binding:"required"`
 	Status   string `json:"status" binding:"required,oneof=Active Inactive Locked"`
 }
 
@@ -29,17 +29,30 @@ func ListUsers(c *gin.Context) {
 		return
 	}
 
+	// Show temporary lockouts, which do not change the status
+	var throttles []models.LoginThrottle
+	if err := config.DB.Where("key LIKE 'user:%' AND locked_until > ?", time.Now()).Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lockouts: " + err.Error()})
+		return
+	}
+	lockedUntil := make(map[string]*time.Time, len(throttles))
+	for _, t := range throttles {
+		lockedUntil[t.Key] = t.LockedUntil
+	}
+
 	// Remove password hashes before sending
 	var sanitized []gin.H
 	for _, u := range users {
 		sanitized = append(sanitized, gin.H{
-			"id":       u.ID,
-			"username": u.Username,
-			"email":    u.Email,
-			"role":     u.Role,
-			"status":   u.Status,
-			"created":  u.CreatedAt,
-			"updated":  u.UpdatedAt,
+			"id":           u.ID,
+			"username":     u.Username,
+			"email":        u.Email,
+			"role":         u.Role,
+			"status":       u.Status,
+			"mfa":          u.MFAEnabled,
+			"locked_until": lockedUntil[userThrottleKey(u.ID)],
+			"created":      u.CreatedAt,
+			"updated":      u.UpdatedAt,
 		})
 	}
 
@@ -71,6 +84,7 @@ func GetUser(c *gin.Context) {
 		"email":    user.Email,
 		"role":     user.Role,
 		"status":   user.Status,
+		"mfa":      user.MFAEnabled,
 		"created":  user.CreatedAt,
 		"updated":  user.UpdatedAt,
 	})
@@ -94,22 +108,28 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// Hash the password
-	pwHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+	if req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+	if status, body := grantableRole(c, models.AdminUserRole(req.Role)); body != nil {
+		c.JSON(status, body)
 		return
 	}
 
 	newUser := models.AdminUser{
-		ID:           uuid.New(),
-		Username:     req.Username,
-		Email:        req.Email,
-		PasswordHash: string(pwHash),
-		Role:         models.AdminUserRole(req.Role),
-		Status:       models.UserStatus(req.Status),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:        uuid.New(),
+		Username:  req.Username,
+		Email:     req.Email,
+		Role:      models.AdminUserRole(req.Role),
+		Status:    models.UserStatus(req.Status),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	// Hash the password
+	if err := setPassword(&newUser, req.Password); err != nil {
+		passwordError(c, err)
+		return
 	}
 
 	if err := config.DB.Create(&newUser).Error; err != nil {
@@ -151,27 +171,60 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	// Role‐change rules
-	requestorRole := c.MustGet("role").(string)
-	if existing.Role == models.RoleSuperAdmin && requestorRole != string(models.RoleSuperAdmin) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify another SUPERADMIN unless you are SUPERADMIN"})
+	// Role‐change rules: the caller must hold every permission of both the
+	// user's current role and the one requested
+	if ok, err := outranks(c, existing.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	} else if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify a user whose role has permissions you do not hold"})
 		return
 	}
-	if req.Role == string(models.RoleSuperAdmin) && requestorRole != string(models.RoleSuperAdmin) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only a SUPERADMIN can promote another user to SUPERADMIN"})
+	if status, body := grantableRole(c, models.AdminUserRole(req.Role)); body != nil {
+		c.JSON(status, body)
 		return
 	}
+	if existing.Role == models.RoleSuperAdmin && req.Role != string(models.RoleSuperAdmin) {
+		var count int64
+		config.DB.Model(&models.AdminUser{}).
+			Where("role = ?", models.RoleSuperAdmin).
+			Count(&count)
+		if count <= 1 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot demote the last remaining SUPERADMIN"})
+			return
+		}
+	}
 
 	existing.Username = req.Username
 	existing.Email = req.Email
 	existing.Role = models.AdminUserRole(req.Role)
 	existing.Status = models.UserStatus(req.Status)
 	existing.UpdatedAt = time.Now()
+	// An empty password leaves the current one in place
+	if req.Password != "" {
+		if err := setPassword(&existing, req.Password); err != nil {
+			passwordError(c, err)
+			return
+		}
+	}
 
 	if err := config.DB.Save(&existing).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user: " + err.Error()})
 		return
 	}
+	revokeReason := ""
+	switch {
+	case existing.Status != models.StatusActive:
+		revokeReason = revokedUserChanged
+	case req.Password != "":
+		revokeReason = revokedPasswordReset
+	}
+	if revokeReason != "" {
+		if err := revokeSessions(config.DB, existing.ID, revokeReason); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User updated but sessions not revoked: " + err.Error()})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"id":       existing.ID,
@@ -201,12 +254,14 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
-	requestorRole := c.MustGet("role").(string)
+	if ok, err := outranks(c, existing.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	} else if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete a user whose role has permissions you do not hold"})
+		return
+	}
 	if existing.Role == models.RoleSuperAdmin {
-		if requestorRole != string(models.RoleSuperAdmin) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only a SUPERADMIN can delete a SUPERADMIN"})
-			return
-		}
 		var count int64
 		config.DB.Model(&models.AdminUser{}).
 			Where("role = ?", models.RoleSuperAdmin).
@@ -221,6 +276,10 @@ func DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user: " + err.Error()})
 		return
 	}
+	if err := revokeSessions(config.DB, uid, revokedUserDeleted); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User deleted but sessions not revoked: " + err.Error()})
+		return
+	}
 
 	c.Status(http.StatusNoContent)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ArowuTest/promo-backend/internal/config"
 	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/rbac"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -56,10 +57,11 @@ func ListWinners(c *gin.Context) {
 		PrizeTier    string `json:"prize_tier"`
 		Position     int    `json:"position"`
 		IsRunnerUp   bool   `json:"is_runner_up"`
+		Status       string `json:"status"`
 	}
 
 	var resp []winnerResponse
-	userRole := c.MustGet("user_role").(string)
+	fullMSISDN := permissions(c).Has(rbac.WinnersFullMSISDN)
 
 	for _, w := range winners {
 		wr := winnerResponse{
@@ -68,8 +70,9 @@ func ListWinners(c *gin.Context) {
 			PrizeTier:    w.PrizeTier.TierName,
 			Position:     w.Position,
 			IsRunnerUp:   w.IsRunnerUp,
+			Status:       w.Status,
 		}
-		if userRole == string(models.RoleSuperAdmin) {
+		if fullMSISDN {
 			wr.MSISDNFull = w.MSISDN
 		}
 		resp = append(resp, wr)
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Categories recorded in DrawExclusionStat for subscribers barred by campaign rules.
+const (
+	ruleCooldown    = "COOLDOWN"
+	ruleMaxWins     = "MAX_WINS"
+	ruleTierLockout = "TIER_LOCKOUT"
+	// ruleBatchWinner bars subscribers who already won an earlier draw of the same batch.
+	ruleBatchWinner = "BATCH_WINNER"
+)
+
+// winnerHistory is what a draw needs to know about earlier winners.
+type winnerHistory struct {
+	// PastWinsByTier holds earlier wins of this structure's tiers, for the
+	// "never win the same tier twice" rule enforced by rng.DrawWinners.
+	PastWinsByTier map[string]map[uuid.UUID]bool
+	// Barred maps MSISDNs removed from the pool by a campaign rule to that rule.
+	// Both maps are keyed by msisdn.Key.
+	Barred map[string]string
+}
+
+// loadWinnerHistory runs indexed queries for the winners relevant to a draw of ps
+// on drawDate. Campaign rules only consider draws dated before drawDate, so a rerun
+// is not blocked by the draw it replaces. Voided wins do not count.
+func loadWinnerHistory(ps models.PrizeStructure, drawDate time.Time) (*winnerHistory, error) {
+	hist := &winnerHistory{
+		PastWinsByTier: make(map[string]map[uuid.UUID]bool),
+		Barred:         make(map[string]string),
+	}
+
+	var tierIDs []uuid.UUID
+	for _, t := range ps.Tiers {
+		tierIDs = append(tierIDs, t.ID)
+	}
+	if len(tierIDs) > 0 {
+		var rows []struct {
+			MSISDN      string
+			PrizeTierID uuid.UUID
+		}
+		if err := config.DB.Model(&models.Winner{}).
+			Select("msisdn, prize_tier_id").
+			Where("prize_tier_id IN ? AND status <> ?", tierIDs, models.WinnerVoided).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			key := msisdn.Key(r.MSISDN)
+			if _, ok := hist.PastWinsByTier[key]; !ok {
+				hist.PastWinsByTier[key] = make(map[uuid.UUID]bool)
+			}
+			hist.PastWinsByTier[key][r.PrizeTierID] = true
+		}
+	}
+
+	if ps.CampaignID == nil {
+		return hist, nil
+	}
+	var camp models.Campaign
+	if err := config.DB.First(&camp, "id = ?", *ps.CampaignID).Error; err != nil {
+		return nil, err
+	}
+
+	campaignWins := func() *gorm.DB {
+		return config.DB.Table("winners").
+			Joins("JOIN draws ON draws.id = winners.draw_id").
+			Joins("JOIN prize_structures ON prize_structures.id = draws.prize_structure_id").
+			Where("prize_structures.campaign_id = ? AND winners.is_runner_up = ? AND winners.status <> ? AND draws.draw_date < ?", camp.ID, false, models.WinnerVoided, drawDate)
+	}
+
+	if camp.CooldownDays > 0 {
+		var msisdns []string
+		if err := campaignWins().
+			Where("draws.draw_date >= ?", drawDate.AddDate(0, 0, -camp.CooldownDays)).
+			Distinct().Pluck("winners.msisdn", &msisdns).Error; err != nil {
+			return nil, err
+		}
+		hist.bar(msisdns, ruleCooldown)
+	}
+
+	if camp.MaxWinsPerMSISDN > 0 {
+		var msisdns []string
+		if err := campaignWins().
+			Group("winners.msisdn").Having("COUNT(*) >= ?", camp.MaxWinsPerMSISDN).
+			Pluck("winners.msisdn", &msisdns).Error; err != nil {
+			return nil, err
+		}
+		hist.bar(msisdns, ruleMaxWins)
+	}
+
+	if len(camp.LockoutTiers) > 0 {
+		var msisdns []string
+		if err := campaignWins().
+			Joins("JOIN prize_tiers ON prize_tiers.id = winners.prize_tier_id").
+			Where("prize_tiers.tier_name IN ?", []string(camp.LockoutTiers)).
+			Distinct().Pluck("winners.msisdn", &msisdns).Error; err != nil {
+			return nil, err
+		}
+		hist.bar(msisdns, ruleTierLockout)
+	}
+
+	return hist, nil
+}
+
+// bar records rule against each of msisdns that is not barred already.
+func (h *winnerHistory) bar(msisdns []string, rule string) {
+	for _, m := range msisdns {
+		key := msisdn.Key(m)
+		if _, already := h.Barred[key]; !already {
+			h.Barred[key] = rule
+		}
+	}
+}
+
+// applyWinnerRules drops entries barred by campaign rules and tallies them per rule.
+func applyWinnerRules(entries []models.EligibleEntry, hist *winnerHistory) ([]models.EligibleEntry, []models.DrawExclusionStat) {
+	if len(hist.Barred) == 0 {
+		return entries, nil
+	}
+	counts := make(map[string]int)
+	var kept []models.EligibleEntry
+	for _, e := range entries {
+		if rule, ok := hist.Barred[msisdn.Key(e.MSISDN)]; ok {
+			counts[rule]++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	var stats []models.DrawExclusionStat
+	for rule, n := range counts {
+		stats = append(stats, models.DrawExclusionStat{ID: uuid.New(), Category: rule, Count: n})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Category < stats[j].Category })
+	return kept, stats
+}
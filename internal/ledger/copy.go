@@ -0,0 +1,157 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// EventSource yields events for Copy one at a time; Next returns io.EOF after
+// the last one. Events with Points set keep them; events with only an amount
+// are scored by the points rules.
+type EventSource interface {
+	Next() (models.RechargeEvent, error)
+}
+
+// stageColumns are the columns of the per-load staging table, in COPY order.
+var stageColumns = []string{"seq", "external_id", "msisdn", "amount_kobo", "channel", "occurred_at", "points", "daily_cap", "rule_set_id"}
+
+const createStage = `CREATE TEMP TABLE recharge_stage (
+	seq         bigint NOT NULL,
+	external_id text NOT NULL,
+	msisdn      text NOT NULL,
+	amount_kobo bigint NOT NULL,
+	channel     text NOT NULL,
+	occurred_at timestamptz NOT NULL,
+	points      integer NOT NULL,
+	daily_cap   integer NOT NULL,
+	rule_set_id uuid
+) ON COMMIT DROP`
+
+// insertFromStage moves staged rows into the ledger. Rows whose external_id is
+// already stored, or repeated in the load, are skipped. Daily caps are applied
+// per MSISDN and Lagos day in time order, counting points already recorded in the
+// ledger (PostHog sync totals are not; the caller holds the LockCaps lock so no other load adds to them meanwhile):
+// with room R left for the day and a running total T that includes the row's
+// own P points, the row keeps LEAST(R, T) - LEAST(R, T - P).
+const insertFromStage = `
+WITH fresh AS (
+	SELECT DISTINCT ON (s.external_id) s.*, (s.occurred_at AT TIME ZONE 'Africa/Lagos')::date AS day
+	FROM recharge_stage s
+	WHERE NOT EXISTS (SELECT 1 FROM recharge_events e WHERE e.external_id = s.external_id)
+	ORDER BY s.external_id, s.seq
+), prior AS (
+	SELECT k.msisdn, k.day, SUM(e.points) AS earned
+	FROM (SELECT DISTINCT msisdn, day FROM fresh WHERE daily_cap > 0) k
+	JOIN recharge_events e ON e.msisdn = k.msisdn
+		AND e.job_id NOT IN (SELECT id FROM ingestion_jobs WHERE source = 'POSTHOG')
+		AND e.occurred_at >= (k.day::timestamp AT TIME ZONE 'Africa/Lagos')
+		AND e.occurred_at < ((k.day + 1)::timestamp AT TIME ZONE 'Africa/Lagos')
+	GROUP BY k.msisdn, k.day
+), running AS (
+	SELECT f.*,
+		SUM(f.points) OVER (PARTITION BY f.msisdn, f.day ORDER BY f.occurred_at, f.seq) AS total,
+		GREATEST(f.daily_cap - COALESCE(p.earned, 0), 0) AS room
+	FROM fresh f
+	LEFT JOIN prior p ON p.msisdn = f.msisdn AND p.day = f.day
+)
+INSERT INTO recharge_events (id, external_id, msisdn, amount_kobo, channel, occurred_at, points, rule_set_id, job_id, created_at)
+SELECT uuid_generate_v4(), external_id, msisdn, amount_kobo, channel, occurred_at,
+	CASE WHEN daily_cap = 0 THEN points ELSE LEAST(room, total) - LEAST(room, total - points) END,
+	rule_set_id, $1, now()
+FROM running
+ON CONFLICT (external_id) DO NOTHING`
+
+// copySource adapts an EventSource to pgx.CopyFromSource, scoring as it goes.
+type copySource struct {
+	src    EventSource
+	scorer *Scorer
+	seq    int64
+	row    []any
+	err    error
+}
+
+func (c *copySource) Next() bool {
+	ev, err := c.src.Next()
+	if err != nil {
+		if err != io.EOF {
+			c.err = err
+		}
+		return false
+	}
+	dailyCap := 0
+	if ev.Points <= 0 {
+		dailyCap = c.scorer.ScoreUncapped(&ev)
+	}
+	ruleSet := pgtype.UUID{}
+	if ev.RuleSetID != nil {
+		ruleSet = pgtype.UUID{Bytes: *ev.RuleSetID, Valid: true}
+	}
+	c.seq++
+	c.row = []any{c.seq, ev.ExternalID, ev.MSISDN, ev.AmountKobo, ev.Channel, ev.OccurredAt, int32(ev.Points), int32(dailyCap), ruleSet}
+	return true
+}
+
+func (c *copySource) Values() ([]any, error) { return c.row, nil }
+
+func (c *copySource) Err() error { return c.err }
+
+// Copy streams src into the ledger under job with COPY, so memory use does not
+// grow with the number of rows. Everything happens in one transaction:
+// beforeCommit, if set, runs after the rows are staged and inserted and can
+// abort the load by returning an error. Job counters are updated on success.
+func Copy(ctx context.Context, db *gorm.DB, job *models.IngestionJob, src EventSource, scorer *Scorer, beforeCommit func() error) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pg, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("ledger: COPY needs a pgx connection, got %T", driverConn)
+		}
+		tx, err := pg.Conn().Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, createStage); err != nil {
+			return fmt.Errorf("ledger: creating staging table: %w", err)
+		}
+		staged, err := tx.CopyFrom(ctx, pgx.Identifier{"recharge_stage"}, stageColumns, &copySource{src: src, scorer: scorer})
+		if err != nil {
+			return fmt.Errorf("ledger: copying events: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(capLockKey)); err != nil {
+			return fmt.Errorf("ledger: taking the daily-cap lock: %w", err)
+		}
+		tag, err := tx.Exec(ctx, insertFromStage, pgtype.UUID{Bytes: job.ID, Valid: true})
+		if err != nil {
+			return fmt.Errorf("ledger: inserting staged events: %w", err)
+		}
+		if beforeCommit != nil {
+			if err := beforeCommit(); err != nil {
+				return err
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		job.Inserted += int(tag.RowsAffected())
+		job.Duplicates += int(staged - tag.RowsAffected())
+		return nil
+	})
+}
@@ -0,0 +1,192 @@
+package ledger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
+	"github.com/ArowuTest/promo-backend/internal/points"
+)
+
+// maxReportedErrors bounds the row errors kept for a failed file.
+const maxReportedErrors = 100
+
+// columnAliases maps accepted header names to canonical column names.
+var columnAliases = map[string]string{
+	"msisdn": "msisdn", "phone": "msisdn", "phone_number": "msisdn",
+	"points": "points",
+	"amount": "amount", "amount_naira": "amount",
+	"timestamp": "timestamp", "occurred_at": "timestamp", "date": "timestamp",
+	"channel":     "channel",
+	"external_id": "external_id", "id": "external_id", "reference": "external_id",
+}
+
+var drawDateInName = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})`)
+
+// DrawDateFromName returns the first yyyy-mm-dd or yyyymmdd date in a file name.
+func DrawDateFromName(name string) *time.Time {
+	m := drawDateInName.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	d, err := time.Parse("2006-01-02", m[1]+"-"+m[2]+"-"+m[3])
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
+// OpenMaybeGzip wraps r in a gzip reader when it starts with the gzip magic bytes.
+func OpenMaybeGzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// CSVSource streams recharge events from an entry CSV with a header row,
+// holding one row in memory at a time. Rows need an MSISDN and either points
+// or a naira amount; rows with points keep them, rows with only an amount are
+// scored by the points rules when stored. Rows without a timestamp are stamped
+// DefaultTime, which must then be non-zero. Rows without an external_id are
+// keyed by KeyPrefix and line number. Invalid rows are skipped and counted.
+type CSVSource struct {
+	reader      *csv.Reader
+	cols        map[string]int
+	keyPrefix   string
+	defaultTime time.Time
+
+	// Rows counts data rows read, Rejected those skipped as invalid, and
+	// Errors describes the first maxReportedErrors of them.
+	Rows     int
+	Rejected int
+	Errors   []string
+}
+
+// NewCSVSource reads and checks the header of r.
+func NewCSVSource(r io.Reader, keyPrefix string, defaultTime time.Time) (*CSVSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		if name, ok := columnAliases[strings.ToLower(strings.TrimSpace(h))]; ok {
+			cols[name] = i
+		}
+	}
+	if _, ok := cols["msisdn"]; !ok {
+		return nil, errors.New("header has no msisdn column")
+	}
+	_, hasPoints := cols["points"]
+	_, hasAmount := cols["amount"]
+	if !hasPoints && !hasAmount {
+		return nil, errors.New("header needs a points or amount column")
+	}
+	return &CSVSource{reader: reader, cols: cols, keyPrefix: keyPrefix, defaultTime: defaultTime}, nil
+}
+
+func (s *CSVSource) field(row []string, name string) string {
+	if i, ok := s.cols[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func (s *CSVSource) reject(line int, format string, args ...any) {
+	s.Rejected++
+	if len(s.Errors) < maxReportedErrors {
+		s.Errors = append(s.Errors, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+	}
+}
+
+// parseTimestamp accepts RFC 3339, "yyyy-mm-dd hh:mm:ss" (Lagos time) and yyyy-mm-dd.
+func parseTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, points.Lagos); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, points.Lagos)
+}
+
+// Next returns the next valid event, or io.EOF after the last row.
+func (s *CSVSource) Next() (models.RechargeEvent, error) {
+	for {
+		row, err := s.reader.Read()
+		if err == io.EOF {
+			return models.RechargeEvent{}, io.EOF
+		}
+		s.Rows++
+		line := s.Rows + 1
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return models.RechargeEvent{}, err
+			}
+			s.reject(line, "%v", err)
+			continue
+		}
+		if ev, ok := s.event(row, line); ok {
+			return ev, nil
+		}
+	}
+}
+
+func (s *CSVSource) event(row []string, line int) (models.RechargeEvent, bool) {
+	number, err := msisdn.Normalize(s.field(row, "msisdn"))
+	if err != nil {
+		s.reject(line, "%v", err)
+		return models.RechargeEvent{}, false
+	}
+	ev := models.RechargeEvent{MSISDN: number, Channel: strings.ToUpper(s.field(row, "channel")), OccurredAt: s.defaultTime}
+	if ts := s.field(row, "timestamp"); ts != "" {
+		if ev.OccurredAt, err = parseTimestamp(ts); err != nil {
+			s.reject(line, "invalid timestamp %q", ts)
+			return models.RechargeEvent{}, false
+		}
+	} else if s.defaultTime.IsZero() {
+		s.reject(line, "timestamp is required when no draw date is given")
+		return models.RechargeEvent{}, false
+	}
+	ev.ExternalID = s.field(row, "external_id")
+	if ev.ExternalID == "" {
+		ev.ExternalID = s.keyPrefix + ":" + strconv.Itoa(line)
+	}
+
+	if amt := s.field(row, "amount"); amt != "" {
+		if ev.AmountKobo, err = points.ParseNaira(amt); err != nil {
+			s.reject(line, "invalid amount %q", amt)
+			return models.RechargeEvent{}, false
+		}
+	}
+	if pts := s.field(row, "points"); pts != "" {
+		n, err := strconv.Atoi(pts)
+		if err != nil || n < 1 {
+			s.reject(line, "points must be a positive integer")
+			return models.RechargeEvent{}, false
+		}
+		ev.Points = n
+	} else if ev.AmountKobo == 0 {
+		s.reject(line, "points or amount is required")
+		return models.RechargeEvent{}, false
+	}
+	return ev, true
+}
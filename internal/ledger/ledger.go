@@ -0,0 +1,181 @@
+// Package ledger stores recharge events and builds draw pools from them.
+//
+// Ingestion jobs append events with their points already computed; draws read
+// the ledger through Pool, bounded by an entry window and an ingestion cutoff,
+// so the same arguments always return the same pool. PostHog syncs store window
+// totals rather than recharges, so their events are only read through JobPool.
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/posthog"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sources recorded on ingestion jobs.
+const (
+	SourcePostHog = "POSTHOG"
+	SourceAPI     = "API"
+	SourceFile    = "FILE"
+	SourceUpload  = "UPLOAD"
+)
+
+// insertBatchSize bounds the rows sent in one INSERT statement.
+const insertBatchSize = 1000
+
+// capLockKey names the advisory lock held while capped points are written.
+const capLockKey = 7410241
+
+// LockCaps takes the daily-cap lock until tx ends. Writers that apply daily
+// caps hold it from reading a day's earned points until their rows are
+// stored, so two loads cannot both see the same total and overshoot the cap.
+func LockCaps(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", capLockKey).Error
+}
+
+// StartJob opens an ingestion job for source; reference names the batch or file.
+func StartJob(db *gorm.DB, source, reference string) (*models.IngestionJob, error) {
+	job := &models.IngestionJob{ID: uuid.New(), Source: source, Reference: reference, Status: models.IngestionRunning, StartedAt: time.Now()}
+	if err := db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// FinishJob records the job's counters and marks it completed, or failed if jobErr is set.
+func FinishJob(db *gorm.DB, job *models.IngestionJob, jobErr error) error {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = models.IngestionCompleted
+	if jobErr != nil {
+		job.Status = models.IngestionFailed
+		job.Error = jobErr.Error()
+	}
+	return db.Save(job).Error
+}
+
+// Record appends events to the ledger under job. Events whose ExternalID is
+// already present are skipped, so replaying a batch is harmless. It returns
+// how many rows were inserted and updates job's Inserted and Duplicates.
+func Record(db *gorm.DB, job *models.IngestionJob, events []models.RechargeEvent) (int, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+	for i := range events {
+		if events[i].ID == uuid.Nil {
+			events[i].ID = uuid.New()
+		}
+		events[i].JobID = job.ID
+	}
+	inserted := 0
+	for start := 0; start < len(events); start += insertBatchSize {
+		end := start + insertBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		res := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "external_id"}}, DoNothing: true}).Create(events[start:end])
+		if res.Error != nil {
+			return inserted, fmt.Errorf("ledger: inserting events: %w", res.Error)
+		}
+		inserted += int(res.RowsAffected)
+	}
+	job.Inserted += inserted
+	job.Duplicates += len(events) - inserted
+	return inserted, nil
+}
+
+// RecordedEvents selects the events that record single recharges, leaving out
+// the totals stored by PostHog syncs. Draw windows overlap, so a synced total
+// would be counted again by every later window that reaches back over it.
+func RecordedEvents(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.RechargeEvent{}).Where("job_id NOT IN (SELECT id FROM ingestion_jobs WHERE source = ?)", SourcePostHog)
+}
+
+// WindowEvents selects the recorded events in [start, end] ingested no later
+// than asOf, the events behind Pool. The query can be reused and refined.
+func WindowEvents(db *gorm.DB, start, end, asOf time.Time) *gorm.DB {
+	return RecordedEvents(db).Where("occurred_at BETWEEN ? AND ? AND created_at <= ?", start, end, asOf).Session(&gorm.Session{})
+}
+
+// JobEvents selects the events stored by one job, the events behind JobPool.
+// The query can be reused and refined.
+func JobEvents(db *gorm.DB, jobID uuid.UUID) *gorm.DB {
+	return db.Model(&models.RechargeEvent{}).Where("job_id = ?", jobID).Session(&gorm.Session{})
+}
+
+// totals turns an events query into per-MSISDN point totals.
+func totals(events *gorm.DB) *gorm.DB {
+	return events.Select("msisdn, SUM(points) AS points").Group("msisdn").Having("SUM(points) > 0")
+}
+
+func windowTotals(db *gorm.DB, start, end, asOf time.Time) *gorm.DB {
+	return totals(WindowEvents(db, start, end, asOf))
+}
+
+func jobTotals(db *gorm.DB, jobID uuid.UUID) *gorm.DB {
+	return totals(JobEvents(db, jobID))
+}
+
+// Pool totals the points of every MSISDN with recorded events in [start, end]
+// that were ingested no later than asOf. Entries are ordered by MSISDN.
+func Pool(db *gorm.DB, start, end, asOf time.Time) ([]models.EligibleEntry, error) {
+	var entries []models.EligibleEntry
+	if err := windowTotals(db, start, end, asOf).Order("msisdn").Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("ledger: building pool: %w", err)
+	}
+	return entries, nil
+}
+
+// JobPool totals the points of every MSISDN among the events stored by job.
+// Entries are ordered by MSISDN.
+func JobPool(db *gorm.DB, jobID uuid.UUID) ([]models.EligibleEntry, error) {
+	var entries []models.EligibleEntry
+	if err := jobTotals(db, jobID).Order("msisdn").Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("ledger: building pool for job %s: %w", jobID, err)
+	}
+	return entries, nil
+}
+
+// SyncReference is the Reference of a PostHog sync job for [start, end].
+func SyncReference(start, end time.Time) string {
+	return fmt.Sprintf("%s/%s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+}
+
+// SyncPostHog copies the PostHog totals for the entry window [start, end] of
+// the draw on drawDate into the ledger. PostHog only reports a points total
+// per MSISDN, so each total becomes one event stamped at end and keyed by job
+// and MSISDN. Each sync is a snapshot that a draw selects through JobPool;
+// RecordedEvents, and so Pool, leave these events out.
+func SyncPostHog(db *gorm.DB, client *posthog.Client, drawDate, start, end time.Time) (*models.IngestionJob, error) {
+	job, err := StartJob(db, SourcePostHog, SyncReference(start, end))
+	if err != nil {
+		return nil, err
+	}
+	job.DrawDate = &drawDate
+	entries, err := client.FetchEligibleEntries(start, end)
+	if err != nil {
+		FinishJob(db, job, err)
+		return job, err
+	}
+	job.Received = len(entries)
+	events := make([]models.RechargeEvent, 0, len(entries))
+	for _, e := range entries {
+		events = append(events, models.RechargeEvent{
+			ExternalID: fmt.Sprintf("posthog:%s:%s", job.ID, e.MSISDN),
+			MSISDN:     e.MSISDN,
+			Channel:    SourcePostHog,
+			OccurredAt: end,
+			Points:     e.Points,
+		})
+	}
+	_, err = Record(db, job, events)
+	if ferr := FinishJob(db, job, err); ferr != nil && err == nil {
+		err = ferr
+	}
+	return job, err
+}
@@ -0,0 +1,172 @@
+package ledger
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/points"
+	"gorm.io/gorm"
+)
+
+// Scorer freezes points onto new recharge events. Each event is scored by the
+// newest rule set in force at its time for the campaign running on that day,
+// falling back to the campaign-less rule sets and then to points.Default.
+// Daily caps count points already in the ledger plus those scored by this Scorer,
+// so a Scorer must not be shared between goroutines, and a capped batch must be
+// scored and recorded in one transaction holding LockCaps.
+type Scorer struct {
+	db        *gorm.DB
+	campaigns []models.Campaign
+	ruleSets  []models.PointsRuleSet
+	earned    map[string]int
+}
+
+// NewScorer loads the campaigns and rule sets used to score events.
+func NewScorer(db *gorm.DB) (*Scorer, error) {
+	s := &Scorer{db: db, earned: make(map[string]int)}
+	if err := db.Order("start_date desc").Find(&s.campaigns).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("effective_from desc, version desc").Find(&s.ruleSets).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RulesOf converts a stored rule set to scoring rules; nil yields points.Default.
+func RulesOf(rs *models.PointsRuleSet) points.Rules {
+	if rs == nil {
+		return points.Default
+	}
+	return points.Rules{KoboPerPoint: rs.KoboPerPoint, ChannelMultipliers: rs.ChannelMultipliers, DayMultipliers: rs.DayMultipliers, DailyCap: rs.DailyCap}
+}
+
+// CampaignAt returns the campaign running on the Lagos day containing at, preferring the latest started.
+func (s *Scorer) CampaignAt(at time.Time) *models.Campaign {
+	day := points.DayStart(at).Format("2006-01-02")
+	for i, c := range s.campaigns {
+		if c.StartDate.Format("2006-01-02") > day {
+			continue
+		}
+		if c.EndDate != nil && c.EndDate.Format("2006-01-02") < day {
+			continue
+		}
+		return &s.campaigns[i]
+	}
+	return nil
+}
+
+// RuleSetAt returns the rule set that scores an event at time at, or nil for the built-in default.
+func (s *Scorer) RuleSetAt(at time.Time) *models.PointsRuleSet {
+	if c := s.CampaignAt(at); c != nil {
+		if rs := s.latest(at, func(rs models.PointsRuleSet) bool { return rs.CampaignID != nil && *rs.CampaignID == c.ID }); rs != nil {
+			return rs
+		}
+	}
+	return s.latest(at, func(rs models.PointsRuleSet) bool { return rs.CampaignID == nil })
+}
+
+func (s *Scorer) latest(at time.Time, match func(models.PointsRuleSet) bool) *models.PointsRuleSet {
+	for i, rs := range s.ruleSets {
+		if match(rs) && !rs.EffectiveFrom.After(at) {
+			return &s.ruleSets[i]
+		}
+	}
+	return nil
+}
+
+// EarnedOn returns the points msisdn has earned on the Lagos day starting at dayStart,
+// including events already scored by s.
+func (s *Scorer) EarnedOn(msisdn string, dayStart time.Time) (int, error) {
+	key := msisdn + "|" + dayStart.Format("2006-01-02")
+	if n, ok := s.earned[key]; ok {
+		return n, nil
+	}
+	var total int
+	err := s.db.Model(&models.RechargeEvent{}).
+		Select("COALESCE(SUM(points), 0)").
+		Where("msisdn = ? AND occurred_at >= ? AND occurred_at < ?", msisdn, dayStart, dayStart.AddDate(0, 0, 1)).
+		Scan(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	s.earned[key] = total
+	return total, nil
+}
+
+// Score sets ev.Points and ev.RuleSetID and returns the scoring breakdown.
+// Events must be scored in the order they will be stored for caps to hold.
+func (s *Scorer) Score(ev *models.RechargeEvent) (points.Score, error) {
+	rs := s.RuleSetAt(ev.OccurredAt)
+	rules := RulesOf(rs)
+	earned := 0
+	dayStart := points.DayStart(ev.OccurredAt)
+	if rules.DailyCap > 0 {
+		var err error
+		if earned, err = s.EarnedOn(ev.MSISDN, dayStart); err != nil {
+			return points.Score{}, err
+		}
+	}
+	score := rules.Score(ev.AmountKobo, ev.Channel, ev.OccurredAt, earned)
+	ev.Points = score.Points
+	ev.RuleSetID = nil
+	if rs != nil {
+		id := rs.ID
+		ev.RuleSetID = &id
+	}
+	if rules.DailyCap > 0 {
+		s.earned[ev.MSISDN+"|"+dayStart.Format("2006-01-02")] = earned + score.Points
+	}
+	return score, nil
+}
+
+// ScoreUncapped sets ev.Points to the event's points before any daily cap and
+// ev.RuleSetID, and returns the daily cap still to be applied (0 for none). It
+// keeps no per-day totals, so it suits streams too large to hold in memory;
+// Copy applies the caps in SQL.
+func (s *Scorer) ScoreUncapped(ev *models.RechargeEvent) int {
+	rs := s.RuleSetAt(ev.OccurredAt)
+	rules := RulesOf(rs)
+	ev.Points = rules.Score(ev.AmountKobo, ev.Channel, ev.OccurredAt, 0).Uncapped
+	ev.RuleSetID = nil
+	if rs != nil {
+		id := rs.ID
+		ev.RuleSetID = &id
+	}
+	return rules.DailyCap
+}
+
+// ScoreInOrder scores events in time order, so caps favour the earliest recharges of a day.
+func (s *Scorer) ScoreInOrder(events []models.RechargeEvent) error {
+	order := make([]int, len(events))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return events[order[a]].OccurredAt.Before(events[order[b]].OccurredAt) })
+	for _, i := range order {
+		if _, err := s.Score(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Existing returns which of externalIDs are already in the ledger.
+func Existing(db *gorm.DB, externalIDs []string) (map[string]bool, error) {
+	found := make(map[string]bool)
+	for start := 0; start < len(externalIDs); start += insertBatchSize {
+		end := start + insertBatchSize
+		if end > len(externalIDs) {
+			end = len(externalIDs)
+		}
+		var ids []string
+		if err := db.Model(&models.RechargeEvent{}).Where("external_id IN ?", externalIDs[start:end]).Pluck("external_id", &ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			found[id] = true
+		}
+	}
+	return found, nil
+}
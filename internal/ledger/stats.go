@@ -0,0 +1,171 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TopEntry is one of the MSISDNs holding the most points in a pool.
+type TopEntry struct {
+	MSISDN string  `json:"msisdn"`
+	Points int64   `json:"points"`
+	Share  float64 `json:"share"`
+}
+
+// PoolStats describes the distribution of points across a pool's MSISDNs.
+// Percentiles are continuous (interpolated) percentiles of per-MSISDN points.
+type PoolStats struct {
+	Distribution
+	Top         []TopEntry `json:"top"`
+	TopPctShare float64    `json:"top_1pct_share"`
+	TopPctCount int64      `json:"top_1pct_entries"`
+}
+
+// Distribution is the part of PoolStats computed in one aggregate query.
+type Distribution struct {
+	Entries     int64   `json:"entries"`
+	TotalPoints int64   `json:"total_points"`
+	MinPoints   int64   `json:"min_points"`
+	MaxPoints   int64   `json:"max_points"`
+	MeanPoints  float64 `json:"mean_points"`
+	P50         float64 `json:"p50"`
+	P90         float64 `json:"p90"`
+	P99         float64 `json:"p99"`
+}
+
+// WindowStats computes the statistics of the pool Pool would return, with
+// the topN largest entries, entirely in SQL.
+func WindowStats(db *gorm.DB, start, end, asOf time.Time, topN int) (*PoolStats, error) {
+	return poolStats(db, windowTotals(db, start, end, asOf), topN)
+}
+
+// JobStats computes the statistics of the pool JobPool would return.
+func JobStats(db *gorm.DB, jobID uuid.UUID, topN int) (*PoolStats, error) {
+	return poolStats(db, jobTotals(db, jobID), topN)
+}
+
+func poolStats(db *gorm.DB, totals *gorm.DB, topN int) (*PoolStats, error) {
+	st := &PoolStats{Top: []TopEntry{}}
+	err := db.Table("(?) AS pool", totals).
+		Select(`COUNT(*) AS entries, COALESCE(SUM(points), 0) AS total_points,
+			COALESCE(MIN(points), 0) AS min_points, COALESCE(MAX(points), 0) AS max_points,
+			COALESCE(AVG(points), 0) AS mean_points,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY points), 0) AS p50,
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY points), 0) AS p90,
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY points), 0) AS p99`).
+		Scan(&st.Distribution).Error
+	if err != nil {
+		return nil, fmt.Errorf("ledger: pool statistics: %w", err)
+	}
+	if st.Entries == 0 {
+		return st, nil
+	}
+
+	if topN > 0 {
+		err = db.Table("(?) AS pool", totals).Select("msisdn, points").
+			Order("points DESC, msisdn").Limit(topN).Scan(&st.Top).Error
+		if err != nil {
+			return nil, fmt.Errorf("ledger: pool top entries: %w", err)
+		}
+		for i := range st.Top {
+			st.Top[i].Share = float64(st.Top[i].Points) / float64(st.TotalPoints)
+		}
+	}
+
+	st.TopPctCount = int64(math.Ceil(float64(st.Entries) / 100))
+	var topPoints int64
+	err = db.Table("(?) AS top", db.Table("(?) AS pool", totals).Select("points").Order("points DESC").Limit(int(st.TopPctCount))).
+		Select("COALESCE(SUM(points), 0)").Scan(&topPoints).Error
+	if err != nil {
+		return nil, fmt.Errorf("ledger: pool concentration: %w", err)
+	}
+	st.TopPctShare = float64(topPoints) / float64(st.TotalPoints)
+	return st, nil
+}
+
+// AnomalyThresholds tune DetectAnomalies.
+type AnomalyThresholds struct {
+	// OutlierFactor flags an MSISDN holding more than this many times the p99 points.
+	OutlierFactor float64 `json:"outlier_factor"`
+	// MaxEntryPoints flags any MSISDN above this many points; 0 disables the check.
+	MaxEntryPoints int64 `json:"max_entry_points"`
+	// MaxTopShare flags a pool where the top 1% hold more than this share of points.
+	MaxTopShare float64 `json:"max_top_share"`
+	// JumpRatio flags entries or total points that grew or shrank by more than
+	// this factor since the previous comparable draw.
+	JumpRatio float64 `json:"jump_ratio"`
+}
+
+// DefaultAnomalyThresholds are used when no thresholds are configured.
+var DefaultAnomalyThresholds = AnomalyThresholds{OutlierFactor: 20, MaxTopShare: 0.5, JumpRatio: 3}
+
+// Anomaly codes.
+const (
+	AnomalyEmptyPool     = "EMPTY_POOL"
+	AnomalyOutlier       = "POINTS_OUTLIER"
+	AnomalyImplausible   = "IMPLAUSIBLE_POINTS"
+	AnomalyConcentration = "CONCENTRATED_POINTS"
+	AnomalyEntriesJump   = "ENTRIES_JUMP"
+	AnomalyPointsJump    = "POINTS_JUMP"
+)
+
+// Anomaly is one suspicious feature of a pool.
+type Anomaly struct {
+	Code      string  `json:"code"`
+	Message   string  `json:"message"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// DetectAnomalies flags implausible point totals in cur and, when prev is
+// given, sudden changes against a previous draw's pool. prev should cover an
+// entry window of the same length, such as the last draw on the same weekday;
+// otherwise a weekly window reads as a jump against a daily one.
+func DetectAnomalies(cur, prev *PoolStats, th AnomalyThresholds) []Anomaly {
+	anomalies := []Anomaly{}
+	if cur.Entries == 0 {
+		return append(anomalies, Anomaly{Code: AnomalyEmptyPool, Message: "The pool has no entries"})
+	}
+	if th.OutlierFactor > 0 && cur.P99 > 0 && float64(cur.MaxPoints) > th.OutlierFactor*cur.P99 {
+		anomalies = append(anomalies, Anomaly{Code: AnomalyOutlier,
+			Message: fmt.Sprintf("An MSISDN holds %d points, over %.0fx the 99th percentile", cur.MaxPoints, th.OutlierFactor),
+			Value:   float64(cur.MaxPoints) / cur.P99, Threshold: th.OutlierFactor})
+	}
+	if th.MaxEntryPoints > 0 && cur.MaxPoints > th.MaxEntryPoints {
+		anomalies = append(anomalies, Anomaly{Code: AnomalyImplausible,
+			Message: fmt.Sprintf("An MSISDN holds %d points, above the plausible maximum", cur.MaxPoints),
+			Value:   float64(cur.MaxPoints), Threshold: float64(th.MaxEntryPoints)})
+	}
+	if th.MaxTopShare > 0 && cur.TopPctShare > th.MaxTopShare {
+		anomalies = append(anomalies, Anomaly{Code: AnomalyConcentration,
+			Message: fmt.Sprintf("The top 1%% of MSISDNs hold %.0f%% of all points", cur.TopPctShare*100),
+			Value:   cur.TopPctShare, Threshold: th.MaxTopShare})
+	}
+	if prev == nil || th.JumpRatio <= 1 {
+		return anomalies
+	}
+	if r, ok := jump(cur.Entries, prev.Entries, th.JumpRatio); ok {
+		anomalies = append(anomalies, Anomaly{Code: AnomalyEntriesJump,
+			Message: fmt.Sprintf("Entries changed from %d to %d since the previous comparable draw", prev.Entries, cur.Entries),
+			Value:   r, Threshold: th.JumpRatio})
+	}
+	if r, ok := jump(cur.TotalPoints, prev.TotalPoints, th.JumpRatio); ok {
+		anomalies = append(anomalies, Anomaly{Code: AnomalyPointsJump,
+			Message: fmt.Sprintf("Total points changed from %d to %d since the previous comparable draw", prev.TotalPoints, cur.TotalPoints),
+			Value:   r, Threshold: th.JumpRatio})
+	}
+	return anomalies
+}
+
+// jump returns cur/prev and whether it is beyond ratio in either direction.
+func jump(cur, prev int64, ratio float64) (float64, bool) {
+	if prev <= 0 {
+		return 0, false
+	}
+	r := float64(cur) / float64(prev)
+	return r, r > ratio || r < 1/ratio
+}
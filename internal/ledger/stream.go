@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrFileRejected wraps the reasons LoadCSV refuses a file, as opposed to
+// failures reading it or storing its rows.
+var ErrFileRejected = errors.New("file rejected")
+
+// progressInterval is how often a streamed load saves its BytesRead.
+const progressInterval = 2 * time.Second
+
+// progressReader counts bytes read into job.BytesRead and saves the count
+// every progressInterval, so the job can be polled while the load runs.
+type progressReader struct {
+	r     io.Reader
+	db    *gorm.DB
+	job   *models.IngestionJob
+	saved time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.job.BytesRead += int64(n)
+	if now := time.Now(); now.Sub(p.saved) >= progressInterval || err == io.EOF {
+		p.saved = now
+		if uerr := p.db.Model(&models.IngestionJob{}).Where("id = ?", p.job.ID).Update("bytes_read", p.job.BytesRead).Error; uerr != nil {
+			log.Printf("ledger: saving progress of job %s: %v", p.job.ID, uerr)
+		}
+	}
+	return n, err
+}
+
+// LoadedChecksum returns the completed job, other than job, that already
+// loaded a file with checksum sum, or nil if there is none.
+func LoadedChecksum(db *gorm.DB, sum string, job *models.IngestionJob) (*models.IngestionJob, error) {
+	var earlier models.IngestionJob
+	err := db.Where("checksum = ? AND status = ? AND id <> ?", sum, models.IngestionCompleted, job.ID).First(&earlier).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &earlier, nil
+}
+
+// LoadCSV streams an entry CSV, optionally gzipped, into the ledger under job.
+// Memory use does not depend on the file's size. The file loads completely or
+// not at all: it is refused if any row is invalid, if it has no rows, or if an
+// identical file was already loaded. Rows without an external_id are keyed by
+// keyPrefix and line number; see CSVSource for the accepted columns. It sets
+// job's checksum, progress and row counters and returns the row errors found.
+func LoadCSV(ctx context.Context, db *gorm.DB, job *models.IngestionJob, r io.Reader, keyPrefix string, defaultTime time.Time) ([]string, error) {
+	hash := sha256.New()
+	counted := &progressReader{r: io.TeeReader(r, hash), db: db, job: job, saved: time.Now()}
+	plain, err := OpenMaybeGzip(counted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFileRejected, err)
+	}
+	src, err := NewCSVSource(plain, keyPrefix, defaultTime)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFileRejected, err)
+	}
+	scorer, err := NewScorer(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Copy(ctx, db, job, src, scorer, func() error {
+		// The CSV is drained by now; read any bytes left after it, such as a
+		// gzip trailer, so the checksum covers the whole file.
+		if _, err := io.Copy(io.Discard, counted); err != nil {
+			return err
+		}
+		job.Checksum = hex.EncodeToString(hash.Sum(nil))
+		job.Received, job.Rejected = src.Rows, src.Rejected
+		if src.Rejected > 0 {
+			return fmt.Errorf("%w: %d of %d rows are invalid", ErrFileRejected, src.Rejected, src.Rows)
+		}
+		if src.Rows == 0 {
+			return fmt.Errorf("%w: file has no rows", ErrFileRejected)
+		}
+		earlier, err := LoadedChecksum(db, job.Checksum, job)
+		if err != nil {
+			return err
+		}
+		if earlier != nil {
+			return fmt.Errorf("%w: identical to %s, already loaded by job %s", ErrFileRejected, earlier.Reference, earlier.ID)
+		}
+		return nil
+	})
+	return src.Errors, err
+}
@@ -0,0 +1,110 @@
+// Package mail sends the few emails the admin backend needs, such as password
+// reset links. Development setups without a mail server can set MAIL_FAKE to
+// keep messages in memory instead.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+
+	"github.com/ArowuTest/promo-backend/internal/config"
+)
+
+// ErrNotConfigured reports that neither SMTP_HOST nor MAIL_FAKE is set.
+var ErrNotConfigured = errors.New("mail: SMTP_HOST is not set; set MAIL_FAKE=true to keep mail in memory during development")
+
+// outboxLimit bounds the messages a FakeSender keeps; older ones are dropped.
+const outboxLimit = 100
+
+// Message is one plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// NewSender returns an SMTP sender when SMTP_HOST is set, the shared Outbox
+// fake when only MAIL_FAKE is, and ErrNotConfigured otherwise, so a
+// misconfigured server cannot silently drop mail.
+func NewSender(cfg *config.AppConfig) (Sender, error) {
+	if cfg.SMTPHost == "" {
+		if cfg.MailFake {
+			return Outbox, nil
+		}
+		return nil, ErrNotConfigured
+	}
+	return &SMTPSender{
+		Addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		Host:     cfg.SMTPHost,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	}, nil
+}
+
+// SMTPSender sends through an SMTP relay, authenticating when Username is set.
+type SMTPSender struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers m. net/smtp takes no context, so ctx is only checked up front.
+func (s *SMTPSender) Send(ctx context.Context, m Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var a smtp.Auth
+	if s.Username != "" {
+		a = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.From, m.To, m.Subject, strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	if err := smtp.SendMail(s.Addr, a, s.From, []string{m.To}, []byte(msg)); err != nil {
+		return fmt.Errorf("mail: send to %s: %w", m.To, err)
+	}
+	return nil
+}
+
+// FakeSender keeps the last outboxLimit messages in memory instead of sending
+// them. Bodies carry secrets such as reset links, so only the recipient and
+// subject are logged.
+type FakeSender struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// Outbox is the fake used when MAIL_FAKE is set.
+var Outbox = &FakeSender{}
+
+// Send records m.
+func (f *FakeSender) Send(_ context.Context, m Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) >= outboxLimit {
+		f.sent = append(f.sent[:0], f.sent[len(f.sent)-outboxLimit+1:]...)
+	}
+	f.sent = append(f.sent, m)
+	log.Printf("mail (not sent, MAIL_FAKE): to=%s subject=%q", m.To, m.Subject)
+	return nil
+}
+
+// Sent returns the messages kept so far, oldest first.
+func (f *FakeSender) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"time"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
@@ -13,12 +14,27 @@ const (
 	RoleSuperAdmin     AdminUserRole = "SUPERADMIN"
 	RoleAdmin          AdminUserRole = "ADMIN"
 	RoleSeniorUser     AdminUserRole = "SENIORUSER"
+	RoleWinnerReports  AdminUserRole = "WINNERREPORTS"
+	RoleAllReports     AdminUserRole = "ALLREPORTS"
 )
 
+// Role is a named set of permissions; AdminUser.Role holds its Name. BuiltIn
+// roles cannot be edited or deleted.
+type Role struct {
+	Name        AdminUserRole  `gorm:"primaryKey"`
+	Description string
+	Permissions pq.StringArray `gorm:"type:text[]"`
+	BuiltIn     bool           `gorm:"not null;default:false"`
+	UpdatedByID *uuid.UUID     `gorm:"type:uuid"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
 type UserStatus string
 const (
 	StatusActive   UserStatus = "Active"
 	StatusInactive UserStatus = "Inactive"
+	StatusLocked   UserStatus = "Locked"
 )
 
 type AdminUser struct {
@@ -28,18 +44,106 @@ type AdminUser struct {
 	PasswordHash string        `gorm:"not null"`
 	Role         AdminUserRole `gorm:"not null"`
 	Status       UserStatus    `gorm:"not null;default:'Active'"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// MFASecret is the sealed TOTP secret once enrolment is confirmed;
+	// MFAPendingSecret holds a new secret until its first code is entered.
+	// MFALastStep is the last TOTP time step accepted, so codes cannot be replayed.
+	MFAEnabled       bool `gorm:"not null;default:false"`
+	MFASecret        string
+	MFAPendingSecret string
+	MFALastStep      int64
+	MFAEnrolledAt    *time.Time
+	// PasswordChangedAt is when the password was last set.
+	PasswordChangedAt *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// LoginThrottle counts failed logins for one key, a user ("user:<id>") or a
+// client IP ("ip:<addr>"). Reaching the limit locks the key until LockedUntil,
+// and each lockout in a row doubles the wait.
+type LoginThrottle struct {
+	Key           string `gorm:"primaryKey"`
+	Failures      int    `gorm:"not null"`
+	Lockouts      int    `gorm:"not null"`
+	LockedUntil   *time.Time
+	LastFailureAt time.Time
+}
+
+// PasswordResetToken lets a user set a new password without the old one. A
+// SUPERADMIN issues it and it is mailed to the user; it works once, until
+// ExpiresAt, and only its hash is stored.
+type PasswordResetToken struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash   string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	UsedAt      *time.Time
+	CreatedByID uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt   time.Time
+}
+
+// MFARecoveryCode is a single-use code that stands in for a TOTP code when
+// the authenticator is lost. Only its hash is stored.
+type MFARecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	CodeHash  string    `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAChallenge is the second step of a login: a password was accepted and
+// the token, stored as a hash, lets the client present a code for a session.
+type MFAChallenge struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	Attempts  int       `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// AdminSession is one login. Access tokens carry the session ID, so revoking
+// the session cuts off every token issued under it.
+type AdminSession struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index"`
+	IP            string
+	UserAgent     string
+	CreatedAt     time.Time
+	LastUsedAt    time.Time
+	RevokedAt     *time.Time
+	RevokedReason string
 }
 
+// RefreshToken is one refresh token of a session, stored as a hash. Each
+// refresh spends the token and issues the next; presenting a spent token
+// again revokes the session.
+type RefreshToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// HashPassword is the one place admin passwords are hashed.
 func HashPassword(pw string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(pw), 14)
 	return string(bytes), err
 }
 
+// EligibleEntry is one subscriber in a draw pool. The attribute fields are
+// optional and only consulted by tiers that restrict eligibility on them.
 type EligibleEntry struct {
-	MSISDN string
-	Points int
+	MSISDN      string
+	Points      int
+	Segment     string
+	Region      string
+	TenureDays  int
+	ProductType string
 }
 
 type WeightedEntry struct {
@@ -48,11 +152,33 @@ type WeightedEntry struct {
 	CumSum int
 }
 
+// Campaign groups prize structures and carries the repeat-winner rules applied to their draws.
+// Zero values disable a rule: CooldownDays bars anyone who won within that many days,
+// MaxWinsPerMSISDN caps wins per subscriber across the campaign and a winner of any
+// tier named in LockoutTiers cannot win again in the campaign.
+type Campaign struct {
+	ID               uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name             string         `gorm:"uniqueIndex;not null"`
+	StartDate        time.Time      `gorm:"not null"`
+	EndDate          *time.Time     `gorm:"index"`
+	CooldownDays     int            `gorm:"not null;default:0"`
+	MaxWinsPerMSISDN int            `gorm:"not null;default:0"`
+	LockoutTiers     pq.StringArray `gorm:"type:text[]"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
 type PrizeStructure struct {
 	ID           uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
 	Name         string         `gorm:"not null"`
+	CampaignID   *uuid.UUID     `gorm:"type:uuid;index"`
 	Effective    time.Time      `gorm:"not null;index"`
+	EndDate      *time.Time     `gorm:"index"`
+	Priority     int            `gorm:"not null;default:0"`
 	EligibleDays pq.StringArray `gorm:"type:text[]"`
+	// WeightStrategy is one of the rng weighting strategies; WeightCap applies to CAPPED.
+	WeightStrategy string `gorm:"not null;default:'LINEAR'"`
+	WeightCap      int    `gorm:"not null;default:0"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	Tiers        []PrizeTier `gorm:"foreignKey:PrizeStructureID;constraint:OnDelete:CASCADE"`
@@ -66,6 +192,94 @@ type PrizeTier struct {
 	Quantity         int       `gorm:"not null;default:1"`
 	RunnerUpCount    int       `gorm:"not null;default:0"`
 	OrderIndex       int       `gorm:"not null;index"`
+	// Eligibility predicates; zero values and empty lists admit everyone.
+	MinPoints     int            `gorm:"not null;default:0"`
+	MinTenureDays int            `gorm:"not null;default:0"`
+	Segments      pq.StringArray `gorm:"type:text[]"`
+	Regions       pq.StringArray `gorm:"type:text[]"`
+	ProductTypes  pq.StringArray `gorm:"type:text[]"`
+}
+
+type CalendarEntryType string
+const (
+	CalendarHoliday   CalendarEntryType = "HOLIDAY"
+	CalendarBlackout  CalendarEntryType = "BLACKOUT"
+	CalendarExtraDraw CalendarEntryType = "EXTRA_DRAW"
+)
+
+// CalendarEntry marks dates on which no draw may run (holidays, blackouts)
+// or on which an extra draw is scheduled outside the structure's eligible days.
+// Holidays and extra draws cover a single day, so StartDate equals EndDate.
+type CalendarEntry struct {
+	ID               uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Type             CalendarEntryType `gorm:"not null;index"`
+	Name             string            `gorm:"not null"`
+	StartDate        time.Time         `gorm:"not null;index"`
+	EndDate          time.Time         `gorm:"not null;index"`
+	PrizeStructureID *uuid.UUID        `gorm:"type:uuid"`
+	CreatedByID      uuid.UUID         `gorm:"type:uuid;not null"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type ExclusionCategory string
+const (
+	ExclusionStaff    ExclusionCategory = "STAFF"
+	ExclusionRelative ExclusionCategory = "RELATIVE"
+	ExclusionFraud    ExclusionCategory = "FRAUD"
+	ExclusionOther    ExclusionCategory = "OTHER"
+)
+
+// ExclusionEntry bars an MSISDN from winning. A nil ExpiresAt makes it permanent.
+type ExclusionEntry struct {
+	ID        uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	MSISDN    string            `gorm:"not null;index"`
+	Category  ExclusionCategory `gorm:"not null"`
+	Reason    string            `gorm:"not null"`
+	ExpiresAt *time.Time        `gorm:"index"`
+	AddedByID uuid.UUID         `gorm:"type:uuid;not null"`
+	AddedBy   AdminUser         `gorm:"foreignKey:AddedByID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DrawTierPool records the filtered pool each tier was drawn from and how far it was filled.
+type DrawTierPool struct {
+	ID                  uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DrawID              uuid.UUID `gorm:"type:uuid;not null;index"`
+	TierName            string    `gorm:"not null"`
+	PoolEntries         int       `gorm:"not null;default:0"`
+	PoolPoints          int       `gorm:"not null;default:0"`
+	ExcludedPastWinners int       `gorm:"not null;default:0"`
+	WinnersRequested    int       `gorm:"not null;default:0"`
+	WinnersDrawn        int       `gorm:"not null;default:0"`
+	RunnerUpsRequested  int       `gorm:"not null;default:0"`
+	RunnerUpsDrawn      int       `gorm:"not null;default:0"`
+}
+
+// DrawSelectionStep is one random pick made while drawing winners, kept so a
+// disputed result can be replayed. RandomWord is the raw 64-bit word in hex and
+// Outcome is an rng.TraceOutcome.
+type DrawSelectionStep struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DrawID      uuid.UUID `gorm:"type:uuid;not null;index:idx_draw_selection_seq,priority:1"`
+	Seq         int       `gorm:"not null;index:idx_draw_selection_seq,priority:2"`
+	TierName    string    `gorm:"not null"`
+	IsRunnerUp  bool      `gorm:"not null;default:false"`
+	RandomWord  string    `gorm:"not null"`
+	TotalWeight int64     `gorm:"not null"`
+	Target      int64     `gorm:"not null"`
+	CumIndex    int       `gorm:"not null"`
+	MSISDN      string    `gorm:"not null"`
+	Outcome     string    `gorm:"not null;default:'SELECTED'"`
+}
+
+// DrawExclusionStat records how many pool entries a draw dropped for one reason.
+type DrawExclusionStat struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DrawID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	Category string    `gorm:"not null"`
+	Count    int       `gorm:"not null;default:0"`
 }
 
 type Draw struct {
@@ -77,23 +291,179 @@ type Draw struct {
 	TotalEntries     int       `gorm:"not null;default:0"`
 	Source           string    `gorm:"not null;default:'PostHog'"`
 	IsRerun          bool      `gorm:"not null;default:false"`
+	ExcludedEntries  int       `gorm:"not null;default:0"`
+	WeightStrategy   string    `gorm:"not null;default:'LINEAR'"`
+	WeightCap        int       `gorm:"not null;default:0"`
+	// WindowStart, WindowEnd and LedgerCutoff pin the recharge ledger rows a LEDGER draw
+	// used, so a rerun selects from exactly the same pool.
+	WindowStart      *time.Time
+	WindowEnd        *time.Time
+	LedgerCutoff     *time.Time
+	// IngestionJobID is set when the pool was exactly the rows of one ingested file.
+	IngestionJobID   *uuid.UUID `gorm:"type:uuid;index"`
+	// BatchID groups draws executed together in one batch; BatchSeq is the draw's place in it.
+	BatchID          *uuid.UUID `gorm:"type:uuid;index"`
+	BatchSeq         int        `gorm:"not null;default:0"`
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
-	Winners          []Winner `gorm:"foreignKey:DrawID;constraint:OnDelete:CASCADE"`
+	Winners          []Winner            `gorm:"foreignKey:DrawID;constraint:OnDelete:CASCADE"`
+	Exclusions       []DrawExclusionStat `gorm:"foreignKey:DrawID;constraint:OnDelete:CASCADE"`
+	TierPools        []DrawTierPool      `gorm:"foreignKey:DrawID;constraint:OnDelete:CASCADE"`
+	FraudFlags       []DrawFraudFlag     `gorm:"foreignKey:DrawID;constraint:OnDelete:CASCADE"`
+}
+
+// PointsRuleSet is one immutable version of the rules that turn recharges into
+// points. Rule sets with a nil CampaignID apply outside any campaign. Multipliers
+// are percentages keyed by channel or upper-case weekday; DailyCap 0 means no cap.
+type PointsRuleSet struct {
+	ID                 uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	CampaignID         *uuid.UUID     `gorm:"type:uuid;uniqueIndex:idx_points_rules_version,priority:1"`
+	Version            int            `gorm:"not null;uniqueIndex:idx_points_rules_version,priority:2"`
+	KoboPerPoint       int64          `gorm:"not null"`
+	ChannelMultipliers map[string]int `gorm:"type:jsonb;serializer:json"`
+	DayMultipliers     map[string]int `gorm:"type:jsonb;serializer:json"`
+	DailyCap           int            `gorm:"not null;default:0"`
+	EffectiveFrom      time.Time      `gorm:"not null;index"`
+	CreatedByID        uuid.UUID      `gorm:"type:uuid;not null"`
+	CreatedAt          time.Time
+}
+
+// Ingestion job statuses.
+const (
+	IngestionRunning   = "RUNNING"
+	IngestionCompleted = "COMPLETED"
+	IngestionFailed    = "FAILED"
+)
+
+// IngestionJob records one load of recharge events into the ledger.
+type IngestionJob struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Source     string    `gorm:"not null;index"`
+	Reference  string
+	Status     string    `gorm:"not null;default:'RUNNING'"`
+	Received   int       `gorm:"not null;default:0"`
+	Inserted   int       `gorm:"not null;default:0"`
+	Duplicates int       `gorm:"not null;default:0"`
+	Rejected   int       `gorm:"not null;default:0"`
+	// Checksum is the SHA-256 of an ingested file; DrawDate is the draw the file was delivered for.
+	Checksum   string     `gorm:"index"`
+	DrawDate   *time.Time `gorm:"index"`
+	// BytesRead and BytesTotal report the progress of a streamed file; BytesTotal is 0 when the size is unknown.
+	BytesRead  int64 `gorm:"not null;default:0"`
+	BytesTotal int64 `gorm:"not null;default:0"`
+	Error      string
+	StartedAt  time.Time  `gorm:"not null"`
+	FinishedAt *time.Time
+}
+
+// RechargeEvent is one recharge in the ledger. Points are computed when the
+// event is ingested and never change afterwards. CreatedAt is the ingestion
+// time, which draws use as a cutoff so that late rows cannot alter a rerun.
+type RechargeEvent struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ExternalID string    `gorm:"not null;uniqueIndex"`
+	MSISDN     string    `gorm:"not null;index:idx_recharge_msisdn_time,priority:1;index:idx_recharge_time_msisdn,priority:2"`
+	AmountKobo int64     `gorm:"not null;default:0"`
+	Channel    string
+	OccurredAt time.Time `gorm:"not null;index:idx_recharge_time_msisdn,priority:1;index:idx_recharge_msisdn_time,priority:2"`
+	Points     int       `gorm:"not null;default:0"`
+	// RuleSetID is the points rule set that scored the event; nil means the built-in default.
+	RuleSetID  *uuid.UUID `gorm:"type:uuid;index"`
+	JobID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+// Fraud screening modes: flagged entries are either dropped from the pool or
+// kept in it with their flags held for review.
+const (
+	FraudModeExclude = "EXCLUDE"
+	FraudModeHold    = "HOLD"
+)
+
+// Review states of a held fraud flag.
+const (
+	FraudReviewPending   = "PENDING"
+	FraudReviewCleared   = "CLEARED"
+	FraudReviewConfirmed = "CONFIRMED"
+)
+
+// FraudSettings configures the fraud screen run while draw pools are built.
+// There is at most one row; a zero threshold disables its check.
+type FraudSettings struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Enabled             bool       `gorm:"not null"`
+	Mode                string     `gorm:"not null;default:'HOLD'"`
+	SequentialBlockMin  int        `gorm:"not null;default:0"`
+	PatternMinNumbers   int        `gorm:"not null;default:0"`
+	PatternMinEvents    int        `gorm:"not null;default:0"`
+	MaxRechargesPerDay  int        `gorm:"not null;default:0"`
+	HistoryFactor       int        `gorm:"not null;default:0"`
+	HistoryMinPoints    int        `gorm:"not null;default:0"`
+	HistoryLookbackDays int        `gorm:"not null;default:0"`
+	UpdatedByID         *uuid.UUID `gorm:"type:uuid"`
+	UpdatedAt           time.Time
 }
 
+// DrawFraudFlag records one fraud rule an MSISDN in a draw's pool tripped.
+// Action is EXCLUDED or HELD; held flags carry a review state.
+type DrawFraudFlag struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DrawID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	MSISDN       string    `gorm:"not null;index"`
+	Rule         string    `gorm:"not null"`
+	Detail       string
+	Points       int        `gorm:"not null;default:0"`
+	Action       string     `gorm:"not null"`
+	ReviewStatus string     `gorm:"index"`
+	ReviewNote   string
+	ReviewedByID *uuid.UUID `gorm:"type:uuid"`
+	ReviewedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+// Winner statuses. A winner is held while a fraud flag raised on its MSISDN in
+// HOLD mode awaits review, and voided once that flag is confirmed.
+const (
+	WinnerAwarded = "AWARDED"
+	WinnerHeld    = "HELD"
+	WinnerVoided  = "VOIDED"
+)
+
 type Winner struct {
 	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	DrawID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	DrawID      uuid.UUID `gorm:"type:uuid;not null;index;index:idx_winners_draw_msisdn,priority:1"`
 	PrizeTierID uuid.UUID `gorm:"type:uuid;not null;index"`
 	PrizeTier   PrizeTier `gorm:"foreignKey:PrizeTierID"`
-	MSISDN      string    `gorm:"not null"`
+	MSISDN      string    `gorm:"not null;index;index:idx_winners_draw_msisdn,priority:2"` // msisdn.Key form
 	Position    int       `gorm:"not null"`
 	IsRunnerUp  bool      `gorm:"not null;default:false"`
+	Status      string    `gorm:"not null;default:'AWARDED'"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
 func Migrate(db *gorm.DB) {
-	db.AutoMigrate(&AdminUser{}, &PrizeStructure{}, &PrizeTier{}, &Draw{}, &Winner{})
+	db.AutoMigrate(&AdminUser{}, &Campaign{}, &PrizeStructure{}, &PrizeTier{}, &Draw{}, &Winner{}, &CalendarEntry{}, &ExclusionEntry{}, &DrawExclusionStat{}, &DrawTierPool{}, &DrawSelectionStep{}, &IngestionJob{}, &PointsRuleSet{}, &RechargeEvent{}, &FraudSettings{}, &DrawFraudFlag{}, &AdminSession{}, &RefreshToken{}, &MFARecoveryCode{}, &MFAChallenge{}, &LoginThrottle{}, &PasswordResetToken{}, &Role{})
+	// idx_points_rules_version cannot number campaign-less rule sets, since
+	// NULL campaign IDs never collide; this partial index does.
+	db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_points_rules_default_version ON points_rule_sets (version) WHERE campaign_id IS NULL")
+	normalizeStoredMSISDNs(db)
+}
+
+// normalizeStoredMSISDNs rewrites winner and fraud flag MSISDNs saved before
+// they were stored in msisdn.Key form. Rows already in that form are skipped,
+// so this is cheap once it has run.
+func normalizeStoredMSISDNs(db *gorm.DB) {
+	for _, table := range []string{"winners", "draw_fraud_flags"} {
+		var rows []struct {
+			ID     uuid.UUID
+			MSISDN string
+		}
+		db.Table(table).Select("id, msisdn").Where("msisdn !~ ?", `^234[0-9]{10}$`).Scan(&rows)
+		for _, r := range rows {
+			if key := msisdn.Key(r.MSISDN); key != r.MSISDN {
+				db.Table(table).Where("id = ?", r.ID).Update("msisdn", key)
+			}
+		}
+	}
 }
\ No newline at end of file
@@ -0,0 +1,60 @@
+// Package msisdn normalizes Nigerian mobile numbers to the international form
+// stored in the ledger: "234" followed by the ten-digit subscriber number.
+package msisdn
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned for numbers that are not Nigerian mobile numbers.
+var ErrInvalid = errors.New("msisdn: not a valid Nigerian mobile number")
+
+const countryCode = "234"
+
+// Normalize accepts local (08031234567), bare (8031234567) and international
+// (+2348031234567, 002348031234567, 2348031234567) forms, with optional spaces,
+// dashes, dots or parentheses, and returns 2348031234567.
+func Normalize(raw string) (string, error) {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+	s = strings.TrimPrefix(s, "+")
+	if strings.HasPrefix(s, "00") {
+		s = s[2:]
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", ErrInvalid
+		}
+	}
+
+	var national string
+	switch {
+	case len(s) == 13 && strings.HasPrefix(s, countryCode):
+		national = s[3:]
+	case len(s) == 11 && s[0] == '0':
+		national = s[1:]
+	case len(s) == 10:
+		national = s
+	default:
+		return "", ErrInvalid
+	}
+	if national[0] < '7' || national[0] > '9' {
+		return "", ErrInvalid
+	}
+	return countryCode + national, nil
+}
+
+// Key is the form used to compare numbers: the normalized number when raw is
+// valid, otherwise raw with surrounding space removed.
+func Key(raw string) string {
+	if n, err := Normalize(raw); err == nil {
+		return n
+	}
+	return strings.TrimSpace(raw)
+}
@@ -0,0 +1,153 @@
+// Package points turns a recharge into draw points under a campaign's rules.
+//
+// Rules are pure data: a base earning rate, optional multipliers for channels
+// and weekdays, and an optional cap on the points one MSISDN may earn per day.
+// Multipliers are percentages, so 150 means one and a half times the base.
+package points
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultKoboPerPoint is the earning rate used when no rule set applies: one point per ₦100.
+const DefaultKoboPerPoint = 100 * 100
+
+// Lagos is the timezone in which weekdays and daily caps are counted.
+var Lagos = time.FixedZone("WAT", 60*60)
+
+// Rules describe how a recharge earns points.
+type Rules struct {
+	KoboPerPoint       int64          `json:"kobo_per_point"`
+	ChannelMultipliers map[string]int `json:"channel_multipliers,omitempty"`
+	DayMultipliers     map[string]int `json:"day_multipliers,omitempty"`
+	DailyCap           int            `json:"daily_cap"`
+}
+
+// Default is the rule set applied when a campaign has none.
+var Default = Rules{KoboPerPoint: DefaultKoboPerPoint}
+
+// Score is the breakdown of how one recharge was scored.
+type Score struct {
+	BasePoints        int    `json:"base_points"`
+	ChannelMultiplier int    `json:"channel_multiplier"`
+	DayMultiplier     int    `json:"day_multiplier"`
+	Uncapped          int    `json:"uncapped_points"`
+	EarnedToday       int    `json:"earned_today"`
+	Points            int    `json:"points"`
+	Capped            bool   `json:"capped"`
+	Day               string `json:"day"`
+}
+
+// Validate rejects rules that cannot score a recharge.
+func (r Rules) Validate() error {
+	if r.KoboPerPoint <= 0 {
+		return errors.New("points: kobo_per_point must be positive")
+	}
+	if r.DailyCap < 0 {
+		return errors.New("points: daily_cap cannot be negative")
+	}
+	for ch, m := range r.ChannelMultipliers {
+		if strings.TrimSpace(ch) == "" || m < 0 {
+			return fmt.Errorf("points: invalid multiplier %d for channel %q", m, ch)
+		}
+	}
+	for day, m := range r.DayMultipliers {
+		if _, ok := weekdays[strings.ToUpper(day)]; !ok {
+			return fmt.Errorf("points: unknown day %q", day)
+		}
+		if m < 0 {
+			return fmt.Errorf("points: invalid multiplier %d for day %q", m, day)
+		}
+	}
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"SUNDAY": time.Sunday, "MONDAY": time.Monday, "TUESDAY": time.Tuesday, "WEDNESDAY": time.Wednesday,
+	"THURSDAY": time.Thursday, "FRIDAY": time.Friday, "SATURDAY": time.Saturday,
+}
+
+// Normalize upper-cases channel and day keys so lookups are case-insensitive.
+func (r Rules) Normalize() Rules {
+	out := Rules{KoboPerPoint: r.KoboPerPoint, DailyCap: r.DailyCap}
+	if len(r.ChannelMultipliers) > 0 {
+		out.ChannelMultipliers = make(map[string]int, len(r.ChannelMultipliers))
+		for ch, m := range r.ChannelMultipliers {
+			out.ChannelMultipliers[strings.ToUpper(strings.TrimSpace(ch))] = m
+		}
+	}
+	if len(r.DayMultipliers) > 0 {
+		out.DayMultipliers = make(map[string]int, len(r.DayMultipliers))
+		for day, m := range r.DayMultipliers {
+			out.DayMultipliers[strings.ToUpper(strings.TrimSpace(day))] = m
+		}
+	}
+	return out
+}
+
+// Score scores a recharge of amountKobo on channel at time at, for an MSISDN
+// that has already earned earnedToday points on that Lagos calendar day.
+// Multipliers apply to the base points and the result is rounded down.
+func (r Rules) Score(amountKobo int64, channel string, at time.Time, earnedToday int) Score {
+	r = r.Normalize()
+	local := at.In(Lagos)
+	s := Score{ChannelMultiplier: 100, DayMultiplier: 100, EarnedToday: earnedToday, Day: strings.ToUpper(local.Weekday().String())}
+	if amountKobo > 0 {
+		s.BasePoints = int(amountKobo / r.KoboPerPoint)
+	}
+	if m, ok := r.ChannelMultipliers[strings.ToUpper(strings.TrimSpace(channel))]; ok {
+		s.ChannelMultiplier = m
+	}
+	if m, ok := r.DayMultipliers[s.Day]; ok {
+		s.DayMultiplier = m
+	}
+	s.Uncapped = int(int64(s.BasePoints) * int64(s.ChannelMultiplier) * int64(s.DayMultiplier) / 10000)
+	s.Points = s.Uncapped
+	if r.DailyCap > 0 {
+		room := r.DailyCap - earnedToday
+		if room < 0 {
+			room = 0
+		}
+		if s.Points > room {
+			s.Points, s.Capped = room, true
+		}
+	}
+	return s
+}
+
+// DayStart returns midnight in Lagos of the day containing t.
+func DayStart(t time.Time) time.Time {
+	local := t.In(Lagos)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, Lagos)
+}
+
+// ErrInvalidAmount is returned by ParseNaira.
+var ErrInvalidAmount = errors.New("amount must be a positive naira value with at most two decimals")
+
+// ParseNaira converts a naira amount such as "1,500.5" to kobo. Only digits,
+// commas and one decimal point are accepted, so a leading sign is rejected.
+func ParseNaira(s string) (int64, error) {
+	whole, frac, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), ".")
+	if whole == "" || len(frac) > 2 || !digits(whole) || !digits(frac) {
+		return 0, ErrInvalidAmount
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	kobo, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || kobo <= 0 {
+		return 0, ErrInvalidAmount
+	}
+	return kobo, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package points
+
+import (
+	"testing"
+	"time"
+)
+
+// 2 June 2025 is a Monday; 23:30 UTC on the 1st is already Monday in Lagos.
+var (
+	monday        = time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	sundayLateUTC = time.Date(2025, 6, 1, 23, 30, 0, 0, time.UTC)
+)
+
+func TestScore(t *testing.T) {
+	rules := Rules{
+		KoboPerPoint:       10000,
+		ChannelMultipliers: map[string]int{"ussd": 150},
+		DayMultipliers:     map[string]int{"Monday": 200},
+		DailyCap:           50,
+	}
+	tests := []struct {
+		name        string
+		rules       Rules
+		amountKobo  int64
+		channel     string
+		at          time.Time
+		earnedToday int
+		want        Score
+	}{
+		{
+			name:       "default rate rounds down",
+			rules:      Default,
+			amountKobo: 19999,
+			at:         monday,
+			want:       Score{BasePoints: 1, ChannelMultiplier: 100, DayMultiplier: 100, Uncapped: 1, Points: 1, Day: "MONDAY"},
+		},
+		{
+			name:       "below one point",
+			rules:      Default,
+			amountKobo: 9999,
+			at:         monday,
+			want:       Score{ChannelMultiplier: 100, DayMultiplier: 100, Day: "MONDAY"},
+		},
+		{
+			name:       "non-positive amount",
+			rules:      Default,
+			amountKobo: -50000,
+			at:         monday,
+			want:       Score{ChannelMultiplier: 100, DayMultiplier: 100, Day: "MONDAY"},
+		},
+		{
+			name:       "channel and day multipliers stack",
+			rules:      rules,
+			amountKobo: 100000,
+			channel:    " USSD ",
+			at:         monday,
+			want:       Score{BasePoints: 10, ChannelMultiplier: 150, DayMultiplier: 200, Uncapped: 30, Points: 30, Day: "MONDAY"},
+		},
+		{
+			name:       "multiplied points round down",
+			rules:      rules,
+			amountKobo: 30000,
+			channel:    "ussd",
+			at:         monday.AddDate(0, 0, 1),
+			want:       Score{BasePoints: 3, ChannelMultiplier: 150, DayMultiplier: 100, Uncapped: 4, Points: 4, Day: "TUESDAY"},
+		},
+		{
+			name:       "weekday is taken in Lagos",
+			rules:      rules,
+			amountKobo: 100000,
+			at:         sundayLateUTC,
+			want:       Score{BasePoints: 10, ChannelMultiplier: 100, DayMultiplier: 200, Uncapped: 20, Points: 20, Day: "MONDAY"},
+		},
+		{
+			name:        "cap leaves the remaining room",
+			rules:       rules,
+			amountKobo:  100000,
+			channel:     "ussd",
+			at:          monday,
+			earnedToday: 40,
+			want:        Score{BasePoints: 10, ChannelMultiplier: 150, DayMultiplier: 200, Uncapped: 30, EarnedToday: 40, Points: 10, Capped: true, Day: "MONDAY"},
+		},
+		{
+			name:        "exactly at the cap is not capped",
+			rules:       rules,
+			amountKobo:  100000,
+			channel:     "ussd",
+			at:          monday,
+			earnedToday: 20,
+			want:        Score{BasePoints: 10, ChannelMultiplier: 150, DayMultiplier: 200, Uncapped: 30, EarnedToday: 20, Points: 30, Day: "MONDAY"},
+		},
+		{
+			name:        "cap already exceeded",
+			rules:       rules,
+			amountKobo:  100000,
+			at:          monday,
+			earnedToday: 70,
+			want:        Score{BasePoints: 10, ChannelMultiplier: 100, DayMultiplier: 200, Uncapped: 20, EarnedToday: 70, Capped: true, Day: "MONDAY"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Score(tt.amountKobo, tt.channel, tt.at, tt.earnedToday); got != tt.want {
+				t.Errorf("Score = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNaira(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"100", 10000, false},
+		{"1,500.5", 150050, false},
+		{" 1500.05 ", 150005, false},
+		{"5.", 500, false},
+		{"0.01", 1, false},
+		{"0", 0, true},
+		{"0.00", 0, true},
+		{"", 0, true},
+		{".5", 0, true},
+		{"1.234", 0, true},
+		{"-5", 0, true},
+		{"+5", 0, true},
+		{"5.+1", 0, true},
+		{"5.-1", 0, true},
+		{"1e3", 0, true},
+		{"₦100", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseNaira(tt.in)
+		if tt.wantErr {
+			if err != ErrInvalidAmount {
+				t.Errorf("ParseNaira(%q) = %d, %v; want ErrInvalidAmount", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseNaira(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
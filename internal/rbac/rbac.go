@@ -0,0 +1,202 @@
+// Package rbac defines the permissions that guard the admin API and the
+// built-in roles granting them. Roles are stored as named permission sets; a
+// SUPERADMIN can edit them and add new ones, except SUPERADMIN itself, which
+// always holds every permission.
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Permission is one action a role can be granted.
+type Permission string
+
+const (
+	UsersManage       Permission = "users:manage"
+	RolesManage       Permission = "roles:manage"
+	CampaignsManage   Permission = "campaigns:manage"
+	PrizesManage      Permission = "prizes:manage"
+	CalendarManage    Permission = "calendar:manage"
+	ExclusionsManage  Permission = "exclusions:manage"
+	PointsManage      Permission = "points:manage"
+	RNGView           Permission = "rng:view"
+	RNGRecover        Permission = "rng:recover"
+	LedgerView        Permission = "ledger:view"
+	LedgerUpload      Permission = "ledger:upload"
+	LedgerSync        Permission = "ledger:sync"
+	ReportsView       Permission = "reports:view"
+	FraudView         Permission = "fraud:view"
+	FraudConfigure    Permission = "fraud:configure"
+	DrawsView         Permission = "draws:view"
+	DrawsSimulate     Permission = "draws:simulate"
+	DrawsExecute      Permission = "draws:execute"
+	DrawsApprove      Permission = "draws:approve"
+	DrawsAudit        Permission = "draws:audit"
+	WinnersView       Permission = "winners:view"
+	WinnersFullMSISDN Permission = "winners:view_full_msisdn"
+)
+
+// Catalogue describes every permission, for role editors.
+var Catalogue = map[Permission]string{
+	UsersManage:       "Create, edit, unlock and delete admin users and reset their passwords and MFA",
+	RolesManage:       "Create, edit and delete roles",
+	CampaignsManage:   "Manage campaigns",
+	PrizesManage:      "Manage prize structures",
+	CalendarManage:    "Manage the draw calendar",
+	ExclusionsManage:  "Manage exclusion lists",
+	PointsManage:      "Manage points rules",
+	RNGView:           "View RNG health",
+	RNGRecover:        "Recover the RNG after a failed health check",
+	LedgerView:        "View ingestion jobs",
+	LedgerUpload:      "Upload entry files to the ledger",
+	LedgerSync:        "Sync the ledger from PostHog",
+	ReportsView:       "View draw pools and pool statistics",
+	FraudView:         "View fraud settings and flags",
+	FraudConfigure:    "Change fraud settings",
+	DrawsView:         "List draws",
+	DrawsSimulate:     "Simulate draws",
+	DrawsExecute:      "Execute, batch and rerun draws",
+	DrawsApprove:      "Review fraud flags, releasing or disqualifying held winners",
+	DrawsAudit:        "View draw selection traces",
+	WinnersView:       "View winners with masked MSISDNs",
+	WinnersFullMSISDN: "See winners' full MSISDNs",
+}
+
+// Sensitive are the permissions that can change who wins or who holds access,
+// or reveal subscribers' numbers. A role granting any of them must use MFA
+// once MFA_ENFORCE is set.
+var Sensitive = []Permission{
+	UsersManage, RolesManage, ExclusionsManage, PointsManage, RNGRecover, LedgerUpload,
+	LedgerSync, FraudConfigure, DrawsExecute, DrawsApprove, WinnersFullMSISDN,
+}
+
+// All returns every permission, sorted.
+func All() []Permission {
+	all := make([]Permission, 0, len(Catalogue))
+	for p := range Catalogue {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	return all
+}
+
+// Defaults are the roles seeded on first start. They reproduce the access the
+// role lists on each route used to grant; WINNERREPORTS and ALLREPORTS are
+// the read-only roles the admin UI offers.
+var Defaults = []models.Role{
+	{Name: models.RoleSuperAdmin, Description: "Full access", BuiltIn: true},
+	{Name: models.RoleAdmin, Description: "Runs campaigns and prepares draws", Permissions: names(
+		CampaignsManage, PrizesManage, CalendarManage, ExclusionsManage, PointsManage, RNGView,
+		LedgerView, LedgerUpload, ReportsView, FraudView, DrawsView, DrawsSimulate, DrawsApprove, WinnersView)},
+	{Name: models.RoleSeniorUser, Description: "Views draws and winners", Permissions: names(DrawsView, WinnersView)},
+	{Name: models.RoleWinnerReports, Description: "Views winner reports", Permissions: names(DrawsView, WinnersView)},
+	{Name: models.RoleAllReports, Description: "Views every report", Permissions: names(DrawsView, WinnersView, ReportsView, LedgerView, FraudView)},
+}
+
+func names(perms ...Permission) []string {
+	out := make([]string, len(perms))
+	for i, p := range perms {
+		out[i] = string(p)
+	}
+	return out
+}
+
+// Set is the permissions one role grants.
+type Set map[Permission]bool
+
+// NewSet builds a Set from stored permission names.
+func NewSet(perms []string) Set {
+	s := make(Set, len(perms))
+	for _, p := range perms {
+		s[Permission(p)] = true
+	}
+	return s
+}
+
+// Has reports whether every one of perms is in s.
+func (s Set) Has(perms ...Permission) bool {
+	for _, p := range perms {
+		if !s[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// NeedsMFA reports whether s grants any Sensitive permission.
+func (s Set) NeedsMFA() bool {
+	for _, p := range Sensitive {
+		if s[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the permissions in s, sorted.
+func (s Set) List() []Permission {
+	list := make([]Permission, 0, len(s))
+	for p := range s {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// Covers reports whether s holds every permission of other, so that a holder
+// of s may hand other out without gaining anything.
+func (s Set) Covers(other Set) bool {
+	for p := range other {
+		if !s[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reports permission names that are not in the Catalogue.
+func Validate(perms []string) error {
+	for _, p := range perms {
+		if _, ok := Catalogue[Permission(p)]; !ok {
+			return fmt.Errorf("unknown permission %q", p)
+		}
+	}
+	return nil
+}
+
+// Seed creates the default roles that do not exist yet and grants SUPERADMIN
+// every permission, including ones added since the last start.
+func Seed(db *gorm.DB) error {
+	for _, r := range Defaults {
+		role := r
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&role).Error; err != nil {
+			return fmt.Errorf("rbac: seed role %s: %w", r.Name, err)
+		}
+	}
+	err := db.Model(&models.Role{}).Where("name = ?", models.RoleSuperAdmin).
+		Updates(map[string]interface{}{"permissions": pq.StringArray(names(All()...)), "built_in": true}).Error
+	if err != nil {
+		return fmt.Errorf("rbac: seed %s: %w", models.RoleSuperAdmin, err)
+	}
+	return nil
+}
+
+// Load returns the permissions of role; an unknown role grants none.
+func Load(db *gorm.DB, role models.AdminUserRole) (Set, error) {
+	var r models.Role
+	err := db.First(&r, "name = ?", role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Set{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewSet(r.Permissions), nil
+}
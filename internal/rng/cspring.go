@@ -8,54 +8,161 @@ import (
     "encoding/binary"
     "fmt"
     "io"
+    "os"
     "sync"
+    "time"
 )
 
-// CSPRNG uses AES-CTR under the hood. It is seeded once from crypto/rand.
+const (
+    // reseedBytes and reseedInterval bound how much output one key produces.
+    reseedBytes    = 1 << 20
+    reseedInterval = time.Hour
+    // selfTestBytes of output must pass the health tests before a failed generator is reused.
+    selfTestBytes = 64 << 10
+)
+
+// CSPRNG uses AES-CTR under the hood. It is seeded from crypto/rand and rekeyed
+// periodically, on demand (once per draw) and whenever the process ID changes.
+// Every output byte passes through continuous health tests; once a test fails the
+// generator refuses to produce output until Recover succeeds.
 type CSPRNG struct {
-    mu       sync.Mutex
-    block    cipher.Block
-    counter  [16]byte   // 128-bit counter for AES-CTR
-    stream   cipher.Stream
+    mu             sync.Mutex
+    stream         cipher.Stream
+    seededAt       time.Time
+    seededPID      int
+    bytesSinceSeed int
+    reseeds        int
+    health         *healthTester
+    failure        error
+}
+
+// HealthStatus is a snapshot of the generator's state for monitoring.
+type HealthStatus struct {
+    Healthy          bool      `json:"healthy"`
+    Failure          string    `json:"failure,omitempty"`
+    LastReseed       time.Time `json:"last_reseed"`
+    BytesSinceReseed int       `json:"bytes_since_reseed"`
+    Reseeds          int       `json:"reseeds"`
 }
 
 // NewCSPRNG initializes an AES-CTR generator seeded from crypto/rand.
 func NewCSPRNG() (*CSPRNG, error) {
+    c := &CSPRNG{health: newHealthTester()}
+    if err := c.reseedLocked(); err != nil {
+        return nil, err
+    }
+    c.reseeds = 0
+    return c, nil
+}
+
+// reseedLocked replaces the key and counter with fresh crypto/rand material.
+func (c *CSPRNG) reseedLocked() error {
     // 1) Generate a 256-bit AES key from crypto/rand
     key := make([]byte, 32)
     if _, err := io.ReadFull(rand.Reader, key); err != nil {
-        return nil, fmt.Errorf("rng: failed to get seed from crypto/rand: %w", err)
+        return fmt.Errorf("rng: failed to get seed from crypto/rand: %w", err)
     }
 
     block, err := aes.NewCipher(key)
     if err != nil {
-        return nil, fmt.Errorf("rng: aes.NewCipher failed: %w", err)
+        return fmt.Errorf("rng: aes.NewCipher failed: %w", err)
     }
 
     // 2) Initialize counter to a random IV (128 bits)
     var iv [16]byte
     if _, err := io.ReadFull(rand.Reader, iv[:]); err != nil {
-        return nil, fmt.Errorf("rng: failed to get IV from crypto/rand: %w", err)
+        return fmt.Errorf("rng: failed to get IV from crypto/rand: %w", err)
     }
 
-    stream := cipher.NewCTR(block, iv[:])
+    c.stream = cipher.NewCTR(block, iv[:])
+    c.seededAt = time.Now()
+    c.seededPID = os.Getpid()
+    c.bytesSinceSeed = 0
+    c.reseeds++
+    return nil
+}
+
+func (c *CSPRNG) needsReseed() bool {
+    return c.bytesSinceSeed >= reseedBytes ||
+        time.Since(c.seededAt) >= reseedInterval ||
+        os.Getpid() != c.seededPID
+}
 
-    return &CSPRNG{
-        block:   block,
-        counter: iv,
-        stream:  stream,
-    }, nil
+// Reseed rekeys the generator from crypto/rand. It fails if a health test has failed.
+func (c *CSPRNG) Reseed() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.failure != nil {
+        return c.failure
+    }
+    return c.reseedLocked()
 }
 
 // Read fills buf with cryptographically secure random bytes (AES-CTR output).
 func (c *CSPRNG) Read(buf []byte) (int, error) {
     c.mu.Lock()
     defer c.mu.Unlock()
-    // Note: Under the hood, AES-CTR XORs a keystream block into buf.
-    c.stream.XORKeyStream(buf, buf) // buf is initially zero, so it becomes keystream
+    if c.failure != nil {
+        return 0, c.failure
+    }
+    if c.needsReseed() {
+        if err := c.reseedLocked(); err != nil {
+            return 0, err
+        }
+    }
+    // AES-CTR XORs the keystream into buf, so clear it first to emit raw keystream.
+    for i := range buf {
+        buf[i] = 0
+    }
+    c.stream.XORKeyStream(buf, buf)
+    c.bytesSinceSeed += len(buf)
+    if err := c.health.check(buf); err != nil {
+        c.failure = err
+        for i := range buf {
+            buf[i] = 0
+        }
+        raiseHealthAlert(err)
+        return 0, err
+    }
     return len(buf), nil
 }
 
+// Health reports whether the generator is usable and when it was last reseeded.
+func (c *CSPRNG) Health() HealthStatus {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    st := HealthStatus{
+        Healthy:          c.failure == nil,
+        LastReseed:       c.seededAt,
+        BytesSinceReseed: c.bytesSinceSeed,
+        Reseeds:          c.reseeds,
+    }
+    if c.failure != nil {
+        st.Failure = c.failure.Error()
+    }
+    return st
+}
+
+// Recover clears a health-test failure after reseeding and checking a fresh
+// block of output. The generator stays blocked if the self-test fails again.
+func (c *CSPRNG) Recover() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if err := c.reseedLocked(); err != nil {
+        return err
+    }
+    c.health = newHealthTester()
+    sample := make([]byte, selfTestBytes)
+    c.stream.XORKeyStream(sample, sample)
+    if err := c.health.check(sample); err != nil {
+        c.failure = err
+        raiseHealthAlert(err)
+        return err
+    }
+    c.failure = nil
+    return c.reseedLocked()
+}
+
 // Uint32 returns a single 32-bit random word.
 func (c *CSPRNG) Uint32() (uint32, error) {
     var b [4]byte
@@ -64,3 +171,12 @@ func (c *CSPRNG) Uint32() (uint32, error) {
     }
     return binary.BigEndian.Uint32(b[:]), nil
 }
+
+// Uint64 returns a single 64-bit random word.
+func (c *CSPRNG) Uint64() (uint64, error) {
+    var b [8]byte
+    if _, err := c.Read(b[:]); err != nil {
+        return 0, err
+    }
+    return binary.BigEndian.Uint64(b[:]), nil
+}
@@ -0,0 +1,114 @@
+package rng
+
+import (
+	"strings"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/ArowuTest/promo-backend/internal/msisdn"
+	"github.com/google/uuid"
+)
+
+// TierPool reports the filtered pool a tier was drawn from and how far the
+// tier was filled. A tier is short when WinnersDrawn < WinnersRequested or
+// RunnerUpsDrawn < RunnerUpsRequested, which only happens once its pool is empty.
+type TierPool struct {
+	TierName            string
+	Entries             int
+	Points              int
+	ExcludedPastWinners int
+	WinnersRequested    int
+	WinnersDrawn        int
+	RunnerUpsRequested  int
+	RunnerUpsDrawn      int
+}
+
+// Unfilled reports whether the tier ran out of eligible entries.
+func (p TierPool) Unfilled() bool {
+	return p.WinnersDrawn < p.WinnersRequested || p.RunnerUpsDrawn < p.RunnerUpsRequested
+}
+
+// mergeEntries folds the rows of each subscriber into one entry, so that tier
+// predicates such as MinPoints see the subscriber's total rather than a single
+// row. Rows are matched by msisdn.Key and the first spelling seen is kept.
+// Points are summed as BuildWeightedEntries sums them, tenure is the longest
+// reported and the other attributes come from the first row that has them.
+func mergeEntries(entries []models.EligibleEntry) []models.EligibleEntry {
+	index := make(map[string]int, len(entries))
+	var merged []models.EligibleEntry
+	for _, e := range entries {
+		key := msisdn.Key(e.MSISDN)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			if e.Points < 0 {
+				e.Points = 0
+			}
+			merged = append(merged, e)
+			continue
+		}
+		m := &merged[i]
+		if e.Points > 0 {
+			m.Points += e.Points
+		}
+		if e.TenureDays > m.TenureDays {
+			m.TenureDays = e.TenureDays
+		}
+		if m.Segment == "" {
+			m.Segment = e.Segment
+		}
+		if m.Region == "" {
+			m.Region = e.Region
+		}
+		if m.ProductType == "" {
+			m.ProductType = e.ProductType
+		}
+	}
+	return merged
+}
+
+// tierAdmits reports whether entry satisfies every eligibility predicate of tier.
+// entry must already be merged by mergeEntries.
+func tierAdmits(tier models.PrizeTier, e models.EligibleEntry) bool {
+	if e.Points < tier.MinPoints {
+		return false
+	}
+	if e.TenureDays < tier.MinTenureDays {
+		return false
+	}
+	return inList(tier.Segments, e.Segment) &&
+		inList(tier.Regions, e.Region) &&
+		inList(tier.ProductTypes, e.ProductType)
+}
+
+// inList treats an empty list as "any value".
+func inList(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// eligibleForTier returns the entries tier may draw from, skipping anyone who
+// has already won in this draw or has won this tier before (pastWinsByTier is
+// keyed by msisdn.Key), and counts the subscribers removed for a past win of
+// this tier.
+func eligibleForTier(entries []models.EligibleEntry, tier models.PrizeTier, winnersThisDraw map[string]bool, pastWinsByTier map[string]map[uuid.UUID]bool) ([]models.EligibleEntry, int) {
+	var filtered []models.EligibleEntry
+	excludedPast := make(map[string]bool)
+	for _, e := range entries {
+		if winnersThisDraw[e.MSISDN] || !tierAdmits(tier, e) {
+			continue
+		}
+		if pastWinsByTier[msisdn.Key(e.MSISDN)][tier.ID] {
+			excludedPast[e.MSISDN] = true
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered, len(excludedPast)
+}
@@ -0,0 +1,75 @@
+package rng
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+)
+
+// Sentinel errors returned by DrawWinners; match them with errors.Is.
+var (
+	// ErrPoolExhausted means no tier could award a single prize.
+	ErrPoolExhausted = errors.New("rng: pool exhausted")
+	// ErrTierUnfillable means at least one tier ran out of eligible entries.
+	ErrTierUnfillable = errors.New("rng: tier could not be filled")
+	// ErrRNGFailure wraps any failure of the random source, including ErrHealthTestFailed.
+	ErrRNGFailure = errors.New("rng: random source failure")
+	// ErrInvalidTierConfig means a tier cannot be drawn as configured.
+	ErrInvalidTierConfig = errors.New("rng: invalid tier configuration")
+)
+
+// TierConfigError names the tier whose configuration was rejected.
+type TierConfigError struct {
+	Tier   string
+	Reason string
+}
+
+func (e *TierConfigError) Error() string {
+	return fmt.Sprintf("%v: tier %q: %s", ErrInvalidTierConfig, e.Tier, e.Reason)
+}
+
+func (e *TierConfigError) Unwrap() error { return ErrInvalidTierConfig }
+
+// PartialFillError accompanies valid results when some tiers ran short.
+// Tiers holds only the unfilled tiers.
+type PartialFillError struct {
+	Tiers []TierPool
+}
+
+func (e *PartialFillError) Error() string {
+	names := make([]string, 0, len(e.Tiers))
+	for _, t := range e.Tiers {
+		names = append(names, t.TierName)
+	}
+	return fmt.Sprintf("%v: %s", ErrTierUnfillable, strings.Join(names, ", "))
+}
+
+func (e *PartialFillError) Unwrap() error { return ErrTierUnfillable }
+
+func rngFailure(err error) error {
+	if errors.Is(err, ErrRNGFailure) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrRNGFailure, err)
+}
+
+// validateTiers rejects tier settings the selection loop cannot honour.
+func validateTiers(tiers []models.PrizeTier) error {
+	seen := make(map[string]bool, len(tiers))
+	for _, t := range tiers {
+		switch {
+		case strings.TrimSpace(t.TierName) == "":
+			return &TierConfigError{Tier: t.TierName, Reason: "name is empty"}
+		case seen[t.TierName]:
+			return &TierConfigError{Tier: t.TierName, Reason: "name is used by more than one tier"}
+		case t.Quantity < 1:
+			return &TierConfigError{Tier: t.TierName, Reason: "quantity must be at least 1"}
+		case t.RunnerUpCount < 0:
+			return &TierConfigError{Tier: t.TierName, Reason: "runner-up count cannot be negative"}
+		}
+		seen[t.TierName] = true
+	}
+	return nil
+}
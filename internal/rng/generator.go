@@ -10,6 +10,11 @@ import (
 
 var csprng *CSPRNG
 
+// source supplies the random words consumed during winner selection.
+type source interface {
+	Uint64() (uint64, error)
+}
+
 func init() {
 	var err error
 	csprng, err = NewCSPRNG()
@@ -25,131 +30,164 @@ type WinnerResult struct {
 	IsRunnerUp bool
 }
 
-func BuildWeightedEntries(entries []models.EligibleEntry) ([]models.WeightedEntry, int) {
-	var weighted []models.WeightedEntry
-	totalPoints := 0
+// BuildWeightedEntries merges entries for the same MSISDN, applies weighting to
+// each subscriber's points and returns the pool, sorted by MSISDN with
+// cumulative sums, together with its total weight.
+func BuildWeightedEntries(entries []models.EligibleEntry, weighting Weighting) ([]models.WeightedEntry, int) {
+	pointsByMSISDN := make(map[string]int, len(entries))
 	for _, e := range entries {
 		if e.Points > 0 {
-			totalPoints += e.Points
-			weighted = append(weighted, models.WeightedEntry{MSISDN: e.MSISDN, Weight: e.Points})
+			pointsByMSISDN[e.MSISDN] += e.Points
+		}
+	}
+
+	var weighted []models.WeightedEntry
+	totalPoints := 0
+	for msisdn, points := range pointsByMSISDN {
+		if w := weighting.Weight(points); w > 0 {
+			totalPoints += w
+			weighted = append(weighted, models.WeightedEntry{MSISDN: msisdn, Weight: w})
 		}
 	}
 	sort.Slice(weighted, func(i, j int) bool { return weighted[i].MSISDN < weighted[j].MSISDN })
+	recomputeCumSums(weighted)
+	return weighted, totalPoints
+}
 
+func recomputeCumSums(weighted []models.WeightedEntry) {
 	cum := 0
 	for i := range weighted {
 		cum += weighted[i].Weight
 		weighted[i].CumSum = cum
 	}
-	return weighted, totalPoints
 }
 
-func pickOneMSISDN(weighted []models.WeightedEntry, totalPoints int) (string, error) {
+// pick is one weighted selection together with the values that produced it.
+type pick struct {
+	MSISDN string
+	Word   uint64
+	Target int
+	Index  int
+}
+
+func pickOneMSISDN(src source, weighted []models.WeightedEntry, totalPoints int) (pick, error) {
 	if totalPoints <= 0 {
-		return "", errors.New("cannot pick from a pool with zero total points")
+		return pick{}, ErrPoolExhausted
 	}
-	u32, err := csprng.Uint32()
+	u64, err := src.Uint64()
 	if err != nil {
-		return "", err
+		return pick{}, rngFailure(err)
 	}
-	r := int(u32 % uint32(totalPoints))
+	r := int(u64 % uint64(totalPoints))
 	idx := sort.Search(len(weighted), func(i int) bool { return r < weighted[i].CumSum })
 	if idx >= len(weighted) {
-		return "", errors.New("rng: index out of range during winner selection")
+		return pick{}, errors.New("rng: index out of range during winner selection")
 	}
-	return weighted[idx].MSISDN, nil
+	return pick{MSISDN: weighted[idx].MSISDN, Word: u64, Target: r, Index: idx}, nil
 }
 
+// DrawWinners draws each tier, in OrderIndex order, from the entries that tier
+// admits, weighted by weighting, and reports the size of every tier's pool.
+// When trace is non-nil every random pick is appended to it.
+//
+// Errors match the sentinels in errors.go. If some tiers ran short the results
+// are still valid and the error is a *PartialFillError naming those tiers; if no
+// prize could be awarded at all it is ErrPoolExhausted. Generator failures,
+// including a blocked generator (ErrHealthTestFailed), match ErrRNGFailure.
 func DrawWinners(
 	entries []models.EligibleEntry,
 	tiers []models.PrizeTier,
 	pastWinsByTier map[string]map[uuid.UUID]bool,
-) ([]WinnerResult, error) {
-	weightedPool, totalPoints := BuildWeightedEntries(entries)
+	weighting Weighting,
+	trace *SelectionTrace,
+) ([]WinnerResult, []TierPool, error) {
+	// A fresh key per draw keeps draws independent even if the process image was cloned.
+	if err := csprng.Reseed(); err != nil {
+		return nil, nil, rngFailure(err)
+	}
+	return drawWinners(csprng, mergeEntries(entries), tiers, pastWinsByTier, weighting, trace)
+}
+
+// drawWinners is DrawWinners with the random source supplied and entries
+// already merged by mergeEntries.
+func drawWinners(
+	src source,
+	entries []models.EligibleEntry,
+	tiers []models.PrizeTier,
+	pastWinsByTier map[string]map[uuid.UUID]bool,
+	weighting Weighting,
+	trace *SelectionTrace,
+) ([]WinnerResult, []TierPool, error) {
+	if err := validateTiers(tiers); err != nil {
+		return nil, nil, err
+	}
 	var finalResults []WinnerResult
+	var pools []TierPool
+	var unfilled []TierPool
 	winnersThisDraw := make(map[string]bool)
 
 	sort.Slice(tiers, func(i, j int) bool { return tiers[i].OrderIndex < tiers[j].OrderIndex })
 
 	for _, tier := range tiers {
-		var mainWinnersForTier []string
-		
-		for i := 0; i < tier.Quantity; i++ {
-			winner, err := drawUniqueWinner(&weightedPool, &totalPoints, winnersThisDraw, pastWinsByTier, tier)
+		candidates, excludedPast := eligibleForTier(entries, tier, winnersThisDraw, pastWinsByTier)
+		weightedPool, totalPoints := BuildWeightedEntries(candidates, weighting)
+		pool := TierPool{TierName: tier.TierName, Entries: len(weightedPool), Points: totalPoints, ExcludedPastWinners: excludedPast, WinnersRequested: tier.Quantity}
+
+		for i := 0; i < tier.Quantity && totalPoints > 0; i++ {
+			winner, err := drawFromPool(src, &weightedPool, &totalPoints, tier, false, trace)
 			if err != nil {
-				if err.Error() == "no eligible winners left" { break }
-				return nil, err
+				return nil, nil, err
 			}
-			mainWinnersForTier = append(mainWinnersForTier, winner)
-		}
-
-		positionCounter := 1
-		for _, winnerMsisdn := range mainWinnersForTier {
-			finalResults = append(finalResults, WinnerResult{TierName: tier.TierName, MSISDN: winnerMsisdn, Position: positionCounter, IsRunnerUp: false})
-			positionCounter++
+			winnersThisDraw[winner] = true
+			pool.WinnersDrawn++
+			finalResults = append(finalResults, WinnerResult{TierName: tier.TierName, MSISDN: winner, Position: pool.WinnersDrawn, IsRunnerUp: false})
 		}
 
-		totalRunnerUpsToDraw := len(mainWinnersForTier) * tier.RunnerUpCount
-		runnerUpPositionCounter := 1
-		for i := 0; i < totalRunnerUpsToDraw; i++ {
-			runnerUp, err := drawUniqueWinner(&weightedPool, &totalPoints, winnersThisDraw, pastWinsByTier, tier)
+		pool.RunnerUpsRequested = pool.WinnersDrawn * tier.RunnerUpCount
+		for i := 0; i < pool.RunnerUpsRequested && totalPoints > 0; i++ {
+			runnerUp, err := drawFromPool(src, &weightedPool, &totalPoints, tier, true, trace)
 			if err != nil {
-				if err.Error() == "no eligible winners left" { break }
-				return nil, err
+				return nil, nil, err
 			}
-			finalResults = append(finalResults, WinnerResult{TierName: tier.TierName, MSISDN: runnerUp, Position: runnerUpPositionCounter, IsRunnerUp: true})
-			runnerUpPositionCounter++
+			winnersThisDraw[runnerUp] = true
+			pool.RunnerUpsDrawn++
+			finalResults = append(finalResults, WinnerResult{TierName: tier.TierName, MSISDN: runnerUp, Position: pool.RunnerUpsDrawn, IsRunnerUp: true})
 		}
+		pools = append(pools, pool)
+		if pool.Unfilled() {
+			unfilled = append(unfilled, pool)
+		}
+	}
+	if len(finalResults) == 0 {
+		return nil, pools, ErrPoolExhausted
+	}
+	if len(unfilled) > 0 {
+		return finalResults, pools, &PartialFillError{Tiers: unfilled}
 	}
-	return finalResults, nil
+	return finalResults, pools, nil
 }
 
-func drawUniqueWinner(
+// drawFromPool makes one weighted pick and removes the chosen MSISDN from the
+// pool. Every pool member is eligible, so each pick is accepted and the draw
+// always terminates; the caller stops once the pool's weight reaches zero.
+func drawFromPool(
+	src source,
 	weightedPool *[]models.WeightedEntry,
 	totalPoints *int,
-	winnersThisDraw map[string]bool,
-	pastWinsByTier map[string]map[uuid.UUID]bool,
 	currentTier models.PrizeTier,
+	isRunnerUp bool,
+	trace *SelectionTrace,
 ) (string, error) {
-	const maxAttempts = 20000 
-	for i := 0; i < maxAttempts; i++ {
-		if *totalPoints <= 0 { return "", errors.New("no eligible winners left") }
-		
-		selectedMsisdn, err := pickOneMSISDN(*weightedPool, *totalPoints)
-		if err != nil { return "", err }
-
-		if winnersThisDraw[selectedMsisdn] { continue }
-		
-		if pastTiersWon, ok := pastWinsByTier[selectedMsisdn]; ok {
-			if _, hasWonThisTier := pastTiersWon[currentTier.ID]; hasWonThisTier {
-				continue
-			}
-		}
-		
-		winnersThisDraw[selectedMsisdn] = true
-
-		var removedWeight int
-		var newPool []models.WeightedEntry
-		for _, entry := range *weightedPool {
-			if entry.MSISDN != selectedMsisdn {
-				newPool = append(newPool, entry)
-			} else {
-				removedWeight = entry.Weight
-			}
-		}
-		
-		if removedWeight > 0 {
-			*weightedPool = newPool
-			*totalPoints -= removedWeight
-			
-			cum := 0
-			for i := range *weightedPool {
-				cum += (*weightedPool)[i].Weight
-				(*weightedPool)[i].CumSum = cum
-			}
-		}
-
-		return selectedMsisdn, nil
+	p, err := pickOneMSISDN(src, *weightedPool, *totalPoints)
+	if err != nil {
+		return "", err
 	}
-	return "", errors.New("max attempts reached to find a unique winner")
-}
\ No newline at end of file
+	trace.record(TraceStep{TierName: currentTier.TierName, IsRunnerUp: isRunnerUp, RandomWord: p.Word, TotalWeight: *totalPoints, Target: p.Target, Index: p.Index, MSISDN: p.MSISDN, Outcome: TraceSelected})
+
+	pool := *weightedPool
+	*totalPoints -= pool[p.Index].Weight
+	pool = append(pool[:p.Index], pool[p.Index+1:]...)
+	recomputeCumSums(pool)
+	*weightedPool = pool
+	return p.MSISDN, nil
+}
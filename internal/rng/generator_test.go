@@ -0,0 +1,118 @@
+package rng
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// seededSource is a deterministic source, so the tests below see the same
+// words on every run.
+type seededSource struct{ r *rand.Rand }
+
+func newSeededSource(seed uint64) *seededSource {
+	return &seededSource{r: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+func (s *seededSource) Uint64() (uint64, error) { return s.r.Uint64(), nil }
+
+// testEntries spreads points widely; 2348030000004 reports its points over
+// three rows, which must count as one subscriber.
+var testEntries = []models.EligibleEntry{
+	{MSISDN: "2348030000001", Points: 1},
+	{MSISDN: "2348030000002", Points: 4},
+	{MSISDN: "2348030000003", Points: 25},
+	{MSISDN: "2348030000004", Points: 40},
+	{MSISDN: "2348030000004", Points: 40},
+	{MSISDN: "2348030000004", Points: 20},
+	{MSISDN: "2348030000005", Points: 400},
+	{MSISDN: "2348030000006", Points: 900},
+}
+
+func TestDrawWinnersPickRates(t *testing.T) {
+	const draws = 20000
+	// p-values below this fail the test; with a fixed seed the outcome is
+	// reproducible, so the bound only needs to catch a biased selection.
+	const minPValue = 0.001
+
+	tests := []struct {
+		name      string
+		weighting Weighting
+	}{
+		{"linear", Weighting{Strategy: WeightLinear}},
+		{"capped", Weighting{Strategy: WeightCapped, Cap: 50}},
+		{"log", Weighting{Strategy: WeightLog}},
+		{"sqrt", Weighting{Strategy: WeightSqrt}},
+		{"one per subscriber", Weighting{Strategy: WeightOnePerSubscriber}},
+	}
+	tier := models.PrizeTier{ID: uuid.New(), TierName: "Grand", Quantity: 1}
+	entries := mergeEntries(testEntries)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, total := BuildWeightedEntries(entries, tt.weighting)
+			if len(pool) != 6 {
+				t.Fatalf("pool has %d subscribers, want 6", len(pool))
+			}
+			src := newSeededSource(uint64(i + 1))
+			counts := make(map[string]int, len(pool))
+			for n := 0; n < draws; n++ {
+				results, _, err := drawWinners(src, entries, []models.PrizeTier{tier}, nil, tt.weighting, nil)
+				if err != nil {
+					t.Fatalf("draw %d: %v", n, err)
+				}
+				if len(results) != 1 {
+					t.Fatalf("draw %d: got %d results, want 1", n, len(results))
+				}
+				counts[results[0].MSISDN]++
+			}
+
+			chi := 0.0
+			for _, e := range pool {
+				expected := float64(draws) * float64(e.Weight) / float64(total)
+				d := float64(counts[e.MSISDN]) - expected
+				chi += d * d / expected
+			}
+			if p := chiSquarePValue(chi, len(pool)-1); p < minPValue {
+				t.Errorf("chi-square %.2f with %d df has p = %.5f; pick rates do not follow weight/total", chi, len(pool)-1, p)
+			}
+		})
+	}
+}
+
+func TestCappedWeighting(t *testing.T) {
+	w := Weighting{Strategy: WeightCapped, Cap: 50}
+	tests := []struct {
+		points, want int
+	}{
+		{0, 0},
+		{-5, 0},
+		{1, 1},
+		{50, 50},
+		{51, 50},
+		{100000, 50},
+	}
+	for _, tt := range tests {
+		if got := w.Weight(tt.points); got != tt.want {
+			t.Errorf("Weight(%d) = %d, want %d", tt.points, got, tt.want)
+		}
+	}
+}
+
+func TestMinPointsUsesSubscriberTotal(t *testing.T) {
+	tier := models.PrizeTier{ID: uuid.New(), TierName: "Grand", Quantity: 1, MinPoints: 90}
+	results, _, err := drawWinners(newSeededSource(1), mergeEntries(testEntries), []models.PrizeTier{tier}, nil, LinearWeighting, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	switch results[0].MSISDN {
+	case "2348030000004", "2348030000005", "2348030000006":
+	default:
+		t.Errorf("winner %s holds fewer than %d points", results[0].MSISDN, tier.MinPoints)
+	}
+}
@@ -0,0 +1,118 @@
+package rng
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+)
+
+// ErrHealthTestFailed is returned by a CSPRNG whose output failed a continuous health test.
+var ErrHealthTestFailed = errors.New("rng: continuous health test failed")
+
+// Continuous health test parameters after SP 800-90B section 4.4, applied to
+// output bytes assumed to carry full entropy (8 bits per sample) with a false
+// alarm probability of 2^-40 per sample.
+const (
+	healthEntropyBits = 8
+	healthAlphaLog2   = 40
+	aptWindow         = 512
+)
+
+var (
+	rctCutoff = 1 + int(math.Ceil(float64(healthAlphaLog2)/healthEntropyBits))
+	aptCutoff = 1 + critBinom(aptWindow, math.Pow(2, -healthEntropyBits), math.Pow(2, -healthAlphaLog2))
+)
+
+// critBinom returns the smallest k with P(Binomial(n, p) > k) <= alpha.
+func critBinom(n int, p, alpha float64) int {
+	lgN, _ := math.Lgamma(float64(n + 1))
+	tail := 1.0
+	for k := 0; k <= n; k++ {
+		lgK, _ := math.Lgamma(float64(k + 1))
+		lgNK, _ := math.Lgamma(float64(n - k + 1))
+		pmf := math.Exp(lgN - lgK - lgNK + float64(k)*math.Log(p) + float64(n-k)*math.Log1p(-p))
+		tail -= pmf
+		if tail <= alpha {
+			return k
+		}
+	}
+	return n
+}
+
+// healthTester runs the repetition count and adaptive proportion tests over a byte stream.
+type healthTester struct {
+	rctLast  byte
+	rctRun   int
+	aptBase  byte
+	aptCount int
+	aptSeen  int
+}
+
+func newHealthTester() *healthTester {
+	return &healthTester{}
+}
+
+func (h *healthTester) check(buf []byte) error {
+	for _, b := range buf {
+		if h.rctRun > 0 && b == h.rctLast {
+			h.rctRun++
+			if h.rctRun >= rctCutoff {
+				return fmt.Errorf("%w: repetition count test saw byte 0x%02x %d times in a row", ErrHealthTestFailed, b, h.rctRun)
+			}
+		} else {
+			h.rctLast = b
+			h.rctRun = 1
+		}
+
+		if h.aptSeen == 0 {
+			h.aptBase = b
+			h.aptCount = 1
+			h.aptSeen = 1
+			continue
+		}
+		if b == h.aptBase {
+			h.aptCount++
+			if h.aptCount >= aptCutoff {
+				return fmt.Errorf("%w: adaptive proportion test saw byte 0x%02x %d times in a %d-byte window", ErrHealthTestFailed, b, h.aptCount, aptWindow)
+			}
+		}
+		h.aptSeen++
+		if h.aptSeen == aptWindow {
+			h.aptSeen = 0
+		}
+	}
+	return nil
+}
+
+var (
+	alertMu     sync.Mutex
+	healthAlert = func(err error) { log.Printf("ALERT: %v; draws are blocked until the RNG is recovered", err) }
+)
+
+// SetHealthAlert replaces the function called when a health test fails.
+func SetHealthAlert(fn func(error)) {
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	healthAlert = fn
+}
+
+func raiseHealthAlert(err error) {
+	alertMu.Lock()
+	fn := healthAlert
+	alertMu.Unlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// Health reports the state of the generator used for live draws.
+func Health() HealthStatus {
+	return csprng.Health()
+}
+
+// Recover clears a health-test failure on the live generator; see CSPRNG.Recover.
+func Recover() error {
+	return csprng.Recover()
+}
@@ -0,0 +1,234 @@
+package rng
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// NISTAlpha is the significance level recommended by SP 800-22.
+const NISTAlpha = 0.01
+
+// Parameters used for the SP 800-22 subset; they suit sequences of 10^6 bits.
+const (
+	nistBlockFrequencyM = 128
+	nistSerialM         = 16
+	nistApEnM           = 10
+)
+
+// NISTMinBits is the shortest sequence RunNISTSuite results are valid for. The
+// serial test needs m < log2(n)-2, so m=16 wants at least 2^19 bits; SP 800-22
+// recommends 10^6.
+const NISTMinBits = 1000000
+
+// NISTResult is the outcome of one SP 800-22 test. Tests producing several
+// p-values (serial, cumulative sums) report each and pass only if all do.
+type NISTResult struct {
+	Name    string    `json:"name"`
+	PValues []float64 `json:"p_values"`
+	Passed  bool      `json:"passed"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// ReadBits reads n bits from r, most significant bit of each byte first,
+// and returns them one per byte as 0 or 1.
+func ReadBits(r io.Reader, n int) ([]uint8, error) {
+	bits := make([]uint8, 0, n)
+	buf := make([]byte, 4096)
+	for len(bits) < n {
+		want := (n - len(bits) + 7) / 8
+		if want > len(buf) {
+			want = len(buf)
+		}
+		if _, err := io.ReadFull(r, buf[:want]); err != nil {
+			return nil, fmt.Errorf("rng: reading bits: %w", err)
+		}
+		for _, b := range buf[:want] {
+			for i := 7; i >= 0 && len(bits) < n; i-- {
+				bits = append(bits, (b>>uint(i))&1)
+			}
+		}
+	}
+	return bits, nil
+}
+
+// RunNISTSuite runs the built-in subset of SP 800-22 on bits: frequency,
+// block frequency, runs, serial, approximate entropy and cumulative sums.
+// bits should hold at least NISTMinBits.
+func RunNISTSuite(bits []uint8) []NISTResult {
+	return []NISTResult{
+		nistResult("Frequency (Monobit)", FrequencyTest(bits)),
+		nistResult(fmt.Sprintf("Block Frequency (M=%d)", nistBlockFrequencyM), BlockFrequencyTest(bits, nistBlockFrequencyM)),
+		runsResult(bits),
+		nistResult(fmt.Sprintf("Serial (m=%d)", nistSerialM), SerialTest(bits, nistSerialM)...),
+		nistResult(fmt.Sprintf("Approximate Entropy (m=%d)", nistApEnM), ApproximateEntropyTest(bits, nistApEnM)),
+		nistResult("Cumulative Sums (forward, backward)", CumulativeSumsTest(bits, false), CumulativeSumsTest(bits, true)),
+	}
+}
+
+func nistResult(name string, pValues ...float64) NISTResult {
+	passed := true
+	for _, p := range pValues {
+		if math.IsNaN(p) || p < NISTAlpha {
+			passed = false
+		}
+	}
+	return NISTResult{Name: name, PValues: pValues, Passed: passed}
+}
+
+func runsResult(bits []uint8) NISTResult {
+	p, ok := RunsTest(bits)
+	res := nistResult("Runs", p)
+	if !ok {
+		res.Note = "frequency prerequisite not met; runs test not applicable"
+	}
+	return res
+}
+
+// FrequencyTest is SP 800-22 section 2.1.
+func FrequencyTest(bits []uint8) float64 {
+	n := len(bits)
+	sum := 0
+	for _, b := range bits {
+		sum += 2*int(b) - 1
+	}
+	sObs := math.Abs(float64(sum)) / math.Sqrt(float64(n))
+	return math.Erfc(sObs / math.Sqrt2)
+}
+
+// BlockFrequencyTest is SP 800-22 section 2.2 with block length m.
+func BlockFrequencyTest(bits []uint8, m int) float64 {
+	blocks := len(bits) / m
+	chi := 0.0
+	for i := 0; i < blocks; i++ {
+		ones := 0
+		for _, b := range bits[i*m : (i+1)*m] {
+			ones += int(b)
+		}
+		pi := float64(ones)/float64(m) - 0.5
+		chi += pi * pi
+	}
+	chi *= 4 * float64(m)
+	return gammaQ(float64(blocks)/2, chi/2)
+}
+
+// RunsTest is SP 800-22 section 2.3. The second result is false when the
+// frequency prerequisite fails, in which case the p-value is 0.
+func RunsTest(bits []uint8) (float64, bool) {
+	n := float64(len(bits))
+	ones := 0
+	for _, b := range bits {
+		ones += int(b)
+	}
+	pi := float64(ones) / n
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(n) {
+		return 0, false
+	}
+	runs := 1
+	for k := 0; k < len(bits)-1; k++ {
+		if bits[k] != bits[k+1] {
+			runs++
+		}
+	}
+	num := math.Abs(float64(runs) - 2*n*pi*(1-pi))
+	den := 2 * math.Sqrt(2*n) * pi * (1 - pi)
+	return math.Erfc(num / den), true
+}
+
+// patternCounts counts overlapping m-bit patterns over bits extended by its
+// first m-1 bits, as the serial and approximate entropy tests require.
+func patternCounts(bits []uint8, m int) []int {
+	if m <= 0 {
+		return nil
+	}
+	n := len(bits)
+	counts := make([]int, 1<<uint(m))
+	mask := (1 << uint(m)) - 1
+	pattern := 0
+	for i := 0; i < m-1; i++ {
+		pattern = (pattern << 1) | int(bits[i])
+	}
+	for i := 0; i < n; i++ {
+		pattern = ((pattern << 1) | int(bits[(i+m-1)%n])) & mask
+		counts[pattern]++
+	}
+	return counts
+}
+
+// psiSquared is the serial test's psi-squared statistic for block length m.
+func psiSquared(bits []uint8, m int) float64 {
+	if m <= 0 {
+		return 0
+	}
+	n := float64(len(bits))
+	sum := 0.0
+	for _, c := range patternCounts(bits, m) {
+		sum += float64(c) * float64(c)
+	}
+	return sum*math.Pow(2, float64(m))/n - n
+}
+
+// SerialTest is SP 800-22 section 2.11; it returns both p-values.
+func SerialTest(bits []uint8, m int) []float64 {
+	psiM := psiSquared(bits, m)
+	psiM1 := psiSquared(bits, m-1)
+	psiM2 := psiSquared(bits, m-2)
+	del1 := psiM - psiM1
+	del2 := psiM - 2*psiM1 + psiM2
+	return []float64{
+		gammaQ(math.Pow(2, float64(m-2)), del1/2),
+		gammaQ(math.Pow(2, float64(m-3)), del2/2),
+	}
+}
+
+// ApproximateEntropyTest is SP 800-22 section 2.12.
+func ApproximateEntropyTest(bits []uint8, m int) float64 {
+	n := float64(len(bits))
+	phi := func(block int) float64 {
+		sum := 0.0
+		for _, c := range patternCounts(bits, block) {
+			if c > 0 {
+				p := float64(c) / n
+				sum += p * math.Log(p)
+			}
+		}
+		return sum
+	}
+	apEn := phi(m) - phi(m+1)
+	chi := 2 * n * (math.Ln2 - apEn)
+	return gammaQ(math.Pow(2, float64(m-1)), chi/2)
+}
+
+// CumulativeSumsTest is SP 800-22 section 2.13, run forward or, if backward is set, in reverse.
+func CumulativeSumsTest(bits []uint8, backward bool) float64 {
+	n := len(bits)
+	s, z := 0, 0
+	for i := 0; i < n; i++ {
+		b := bits[i]
+		if backward {
+			b = bits[n-1-i]
+		}
+		s += 2*int(b) - 1
+		if abs := int(math.Abs(float64(s))); abs > z {
+			z = abs
+		}
+	}
+	if z == 0 {
+		return 0
+	}
+	nf, zf := float64(n), float64(z)
+	sqrtN := math.Sqrt(nf)
+	phi := func(x float64) float64 { return 0.5 * math.Erfc(-x/math.Sqrt2) }
+
+	sum1 := 0.0
+	for k := int((-nf/zf + 1) / 4); k <= int((nf/zf-1)/4); k++ {
+		kf := float64(k)
+		sum1 += phi((4*kf+1)*zf/sqrtN) - phi((4*kf-1)*zf/sqrtN)
+	}
+	sum2 := 0.0
+	for k := int((-nf/zf - 3) / 4); k <= int((nf/zf-1)/4); k++ {
+		kf := float64(k)
+		sum2 += phi((4*kf+3)*zf/sqrtN) - phi((4*kf+1)*zf/sqrtN)
+	}
+	return 1 - sum1 + sum2
+}
@@ -0,0 +1,83 @@
+package rng
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// The cases below are the worked examples in SP 800-22 rev 1a, section 2.x;
+// the document prints P-values to six decimal places.
+const nistTolerance = 1e-6
+
+// nistExample100 is the 100-bit sequence used by several section 2 examples.
+const nistExample100 = "1100100100001111110110101010001000100001011010001100001000110100110001001100011001100010100010111000"
+
+func bitString(s string) []uint8 {
+	bits := make([]uint8, len(s))
+	for i, r := range s {
+		bits[i] = uint8(r - '0')
+	}
+	return bits
+}
+
+func checkP(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > nistTolerance {
+		t.Errorf("%s: P-value = %.6f, want %.6f", name, got, want)
+	}
+}
+
+func TestFrequencyTest(t *testing.T) {
+	checkP(t, "2.1.4", FrequencyTest(bitString("1011010101")), 0.527089)
+	checkP(t, "2.1.8", FrequencyTest(bitString(nistExample100)), 0.109599)
+}
+
+func TestBlockFrequencyTest(t *testing.T) {
+	checkP(t, "2.2.4", BlockFrequencyTest(bitString("0110011010"), 3), 0.801252)
+	checkP(t, "2.2.8", BlockFrequencyTest(bitString(nistExample100), 10), 0.706438)
+}
+
+func TestRunsTest(t *testing.T) {
+	tests := []struct {
+		name string
+		bits string
+		want float64
+	}{
+		{"2.3.4", "1001101011", 0.147232},
+		{"2.3.8", nistExample100, 0.500798},
+	}
+	for _, tt := range tests {
+		p, ok := RunsTest(bitString(tt.bits))
+		if !ok {
+			t.Errorf("%s: frequency prerequisite failed", tt.name)
+			continue
+		}
+		checkP(t, tt.name, p, tt.want)
+	}
+}
+
+func TestRunsTestPrerequisite(t *testing.T) {
+	// With n = 100 the prerequisite allows |pi - 1/2| < 0.2; pi here is 0.9.
+	bits := bitString(strings.Repeat("1111111110", 10))
+	if p, ok := RunsTest(bits); ok || p != 0 {
+		t.Errorf("RunsTest on a 90%% ones sequence = (%v, %v), want (0, false)", p, ok)
+	}
+}
+
+func TestSerialTest(t *testing.T) {
+	p := SerialTest(bitString("0011011101"), 3)
+	checkP(t, "2.11.4 P1", p[0], 0.808792)
+	checkP(t, "2.11.4 P2", p[1], 0.670320)
+}
+
+func TestApproximateEntropyTest(t *testing.T) {
+	checkP(t, "2.12.4", ApproximateEntropyTest(bitString("0100110101"), 3), 0.261961)
+	checkP(t, "2.12.8", ApproximateEntropyTest(bitString(nistExample100), 2), 0.235301)
+}
+
+func TestCumulativeSumsTest(t *testing.T) {
+	checkP(t, "2.13.4", CumulativeSumsTest(bitString("1011010111"), false), 0.4116588)
+	checkP(t, "2.13.8 forward", CumulativeSumsTest(bitString(nistExample100), false), 0.219194)
+	checkP(t, "2.13.8 backward", CumulativeSumsTest(bitString(nistExample100), true), 0.114866)
+}
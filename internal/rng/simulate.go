@@ -0,0 +1,148 @@
+package rng
+
+import (
+	"errors"
+	"math"
+
+	"github.com/ArowuTest/promo-backend/internal/models"
+)
+
+const (
+	// MaxSimulationIterations bounds a single simulation run.
+	MaxSimulationIterations = 100000
+	// MaxSimulationWork bounds iterations times pool size, since every
+	// iteration rebuilds and searches the whole pool.
+	MaxSimulationWork = 10000000
+)
+
+// ErrSimulationTooLarge rejects a run whose iterations times pool size exceeds
+// MaxSimulationWork.
+var ErrSimulationTooLarge = errors.New("rng: simulation too large for the pool size")
+
+// MaxIterationsFor returns the most iterations Simulate accepts for a pool of
+// poolSize subscribers.
+func MaxIterationsFor(poolSize int) int {
+	if poolSize < 1 {
+		return MaxSimulationIterations
+	}
+	return min(MaxSimulationIterations, MaxSimulationWork/poolSize)
+}
+
+// MSISDNRate compares how often an MSISDN was selected with how often it should be.
+//
+// The first pick of the first tier is drawn from the whole tier pool, so its
+// expected rate is exactly the entry's share of the pool weight; that is what the
+// goodness-of-fit tests check. WinRate counts any prize, including runner-up spots.
+type MSISDNRate struct {
+	MSISDN            string  `json:"msisdn"`
+	Weight            int     `json:"weight"`
+	ExpectedFirstPick float64 `json:"expected_first_pick_rate"`
+	FirstPick         float64 `json:"first_pick_rate"`
+	WinRate           float64 `json:"win_rate"`
+}
+
+// GoodnessOfFit holds chi-square and Kolmogorov-Smirnov results for the first-pick distribution.
+// The chi-square approximation is only reliable when MinExpectedCount is at least 5.
+type GoodnessOfFit struct {
+	ChiSquare        float64 `json:"chi_square"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+	ChiSquarePValue  float64 `json:"chi_square_p_value"`
+	KSStatistic      float64 `json:"ks_statistic"`
+	KSPValue         float64 `json:"ks_p_value"`
+	MinExpectedCount float64 `json:"min_expected_count"`
+}
+
+// SimulationResult is the outcome of running DrawWinners repeatedly on one pool.
+type SimulationResult struct {
+	Iterations int           `json:"iterations"`
+	FirstTier  string        `json:"first_tier"`
+	Samples    int           `json:"samples"`
+	Rates      []MSISDNRate  `json:"rates"`
+	Fit        GoodnessOfFit `json:"goodness_of_fit"`
+}
+
+// Simulate runs the production selection algorithm iterations times on entries
+// using a throwaway CSPRNG, so the live generator's stream is never consumed.
+// Past winners are ignored; the simulation only measures the sampling itself.
+func Simulate(entries []models.EligibleEntry, tiers []models.PrizeTier, weighting Weighting, iterations int) (*SimulationResult, error) {
+	if iterations < 1 || iterations > MaxSimulationIterations {
+		return nil, errors.New("rng: simulation iterations out of range")
+	}
+	if len(tiers) == 0 {
+		return nil, errors.New("rng: simulation needs at least one tier")
+	}
+	if err := weighting.Validate(); err != nil {
+		return nil, err
+	}
+	src, err := NewCSPRNG()
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := append([]models.PrizeTier(nil), tiers...)
+	firstTier := ordered[0]
+	for _, t := range ordered {
+		if t.OrderIndex < firstTier.OrderIndex {
+			firstTier = t
+		}
+	}
+	entries = mergeEntries(entries)
+	if iterations > MaxIterationsFor(len(entries)) {
+		return nil, ErrSimulationTooLarge
+	}
+	candidates, _ := eligibleForTier(entries, firstTier, nil, nil)
+	pool, totalWeight := BuildWeightedEntries(candidates, weighting)
+	if totalWeight <= 0 {
+		return nil, errors.New("rng: first tier pool is empty")
+	}
+
+	firstPicks := make(map[string]int, len(pool))
+	wins := make(map[string]int, len(pool))
+	samples := 0
+	for i := 0; i < iterations; i++ {
+		results, _, err := drawWinners(src, entries, ordered, nil, weighting, nil)
+		if err != nil && !errors.Is(err, ErrTierUnfillable) {
+			return nil, err
+		}
+		if len(results) > 0 && results[0].TierName == firstTier.TierName && !results[0].IsRunnerUp {
+			firstPicks[results[0].MSISDN]++
+			samples++
+		}
+		for _, r := range results {
+			wins[r.MSISDN]++
+		}
+	}
+
+	res := &SimulationResult{Iterations: iterations, FirstTier: firstTier.TierName, Samples: samples}
+	res.Fit.MinExpectedCount = math.Inf(1)
+	var cumExpected, cumObserved float64
+	for _, e := range pool {
+		expected := float64(e.Weight) / float64(totalWeight)
+		observed := 0.0
+		if samples > 0 {
+			observed = float64(firstPicks[e.MSISDN]) / float64(samples)
+		}
+		res.Rates = append(res.Rates, MSISDNRate{
+			MSISDN:            e.MSISDN,
+			Weight:            e.Weight,
+			ExpectedFirstPick: expected,
+			FirstPick:         observed,
+			WinRate:           float64(wins[e.MSISDN]) / float64(iterations),
+		})
+
+		expectedCount := expected * float64(samples)
+		if expectedCount > 0 {
+			diff := float64(firstPicks[e.MSISDN]) - expectedCount
+			res.Fit.ChiSquare += diff * diff / expectedCount
+		}
+		res.Fit.MinExpectedCount = math.Min(res.Fit.MinExpectedCount, expectedCount)
+
+		cumExpected += expected
+		cumObserved += observed
+		res.Fit.KSStatistic = math.Max(res.Fit.KSStatistic, math.Abs(cumObserved-cumExpected))
+	}
+	res.Fit.DegreesOfFreedom = len(pool) - 1
+	res.Fit.ChiSquarePValue = chiSquarePValue(res.Fit.ChiSquare, res.Fit.DegreesOfFreedom)
+	res.Fit.KSPValue = ksPValue(res.Fit.KSStatistic, samples)
+	return res, nil
+}
@@ -0,0 +1,104 @@
+package rng
+
+import "math"
+
+const (
+	statsMaxIterations = 1000
+	statsEpsilon       = 1e-15
+	statsTiny          = 1e-300
+)
+
+// chiSquarePValue is the probability of a chi-square statistic at least x with df degrees of freedom.
+func chiSquarePValue(x float64, df int) float64 {
+	if df <= 0 {
+		return 1
+	}
+	return gammaQ(float64(df)/2, x/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x).
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	if x < a+1 {
+		return 1 - gammaPSeries(a, x)
+	}
+	return gammaQFraction(a, x)
+}
+
+// gammaPSeries evaluates P(a, x) by its series expansion, which converges quickly for x < a+1.
+func gammaPSeries(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	ap := a
+	sum := 1 / a
+	del := sum
+	for i := 0; i < statsMaxIterations; i++ {
+		ap++
+		del *= x / ap
+		sum += del
+		if math.Abs(del) < math.Abs(sum)*statsEpsilon {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lg)
+}
+
+// gammaQFraction evaluates Q(a, x) by Lentz's continued fraction, used for x >= a+1.
+func gammaQFraction(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / statsTiny
+	d := 1 / b
+	h := d
+	for i := 1; i < statsMaxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < statsTiny {
+			d = statsTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < statsTiny {
+			c = statsTiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < statsEpsilon {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+// ksPValue approximates the Kolmogorov-Smirnov p-value for statistic d over n samples.
+// For discrete distributions the result is conservative.
+func ksPValue(d float64, n int) float64 {
+	if n <= 0 {
+		return 1
+	}
+	sqrtN := math.Sqrt(float64(n))
+	lambda := (sqrtN + 0.12 + 0.11/sqrtN) * d
+	if lambda < 0.2 {
+		return 1
+	}
+	sum := 0.0
+	sign := 1.0
+	for j := 1; j <= 100; j++ {
+		term := sign * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < statsEpsilon*math.Abs(sum) {
+			break
+		}
+		sign = -sign
+	}
+	p := 2 * sum
+	if p < 0 {
+		return 0
+	}
+	if p > 1 {
+		return 1
+	}
+	return p
+}
@@ -0,0 +1,36 @@
+package rng
+
+// TraceOutcome says what happened to one random pick.
+type TraceOutcome string
+
+const TraceSelected TraceOutcome = "SELECTED"
+
+// TraceStep records one random word consumed during selection: the word, the
+// target it reduced to modulo the pool weight, the cumulative-sum index that
+// target landed on, and whether the MSISDN there was accepted. Ineligible
+// MSISDNs are removed from a tier's pool before sampling, so every step is
+// TraceSelected; the number removed is reported per tier in TierPool.
+type TraceStep struct {
+	Seq         int
+	TierName    string
+	IsRunnerUp  bool
+	RandomWord  uint64
+	TotalWeight int
+	Target      int
+	Index       int
+	MSISDN      string
+	Outcome     TraceOutcome
+}
+
+// SelectionTrace collects every pick made by a draw. A nil trace records nothing.
+type SelectionTrace struct {
+	Steps []TraceStep
+}
+
+func (t *SelectionTrace) record(step TraceStep) {
+	if t == nil {
+		return
+	}
+	step.Seq = len(t.Steps) + 1
+	t.Steps = append(t.Steps, step)
+}
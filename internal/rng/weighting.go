@@ -0,0 +1,67 @@
+package rng
+
+import (
+	"fmt"
+	"math"
+)
+
+// WeightStrategy names how an entry's points become its selection weight.
+type WeightStrategy string
+
+const (
+	WeightLinear           WeightStrategy = "LINEAR"
+	WeightCapped           WeightStrategy = "CAPPED"
+	WeightLog              WeightStrategy = "LOG"
+	WeightSqrt             WeightStrategy = "SQRT"
+	WeightOnePerSubscriber WeightStrategy = "ONE_PER_SUBSCRIBER"
+)
+
+// weightScale keeps the fractional part of logarithmic and square-root weights
+// while the cumulative sums stay integral.
+const weightScale = 1000
+
+// Weighting is a strategy plus its parameter (the cap, for WeightCapped).
+type Weighting struct {
+	Strategy WeightStrategy
+	Cap      int
+}
+
+// LinearWeighting uses raw points as weight.
+var LinearWeighting = Weighting{Strategy: WeightLinear}
+
+// Validate rejects unknown strategies and caps that would zero every weight.
+func (w Weighting) Validate() error {
+	switch w.Strategy {
+	case WeightLinear, WeightLog, WeightSqrt, WeightOnePerSubscriber:
+		return nil
+	case WeightCapped:
+		if w.Cap < 1 {
+			return fmt.Errorf("rng: %s weighting needs a cap of at least 1", w.Strategy)
+		}
+		return nil
+	default:
+		return fmt.Errorf("rng: unknown weighting strategy %q", w.Strategy)
+	}
+}
+
+// Weight converts points to a selection weight. Entries with no points weigh nothing.
+func (w Weighting) Weight(points int) int {
+	if points <= 0 {
+		return 0
+	}
+	switch w.Strategy {
+	case WeightCapped:
+		if points > w.Cap {
+			return w.Cap
+		}
+		return points
+	case WeightLog:
+		return int(math.Round(weightScale * (1 + math.Log(float64(points)))))
+	case WeightSqrt:
+		return int(math.Round(weightScale * math.Sqrt(float64(points))))
+	case WeightOnePerSubscriber:
+		return 1
+	default:
+		return points
+	}
+}